@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rschio/repoTagger/repo"
+)
+
+// fetchReleases fetches the latest release of repos and stores
+// the ones that changed, it returns how many were stored.
+func (s *server) fetchReleases(ctx context.Context, src repo.ReleaseFetcher, repos []*repo.Repo) int {
+	return fetchEach(ctx, repos, "release", func(r *repo.Repo) (func() bool, error) {
+		at, err := src.LatestRelease(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return func() bool {
+			old, err := s.store.GetRepo(r.ID)
+			if err != nil || old.ReleasedAt.Equal(at) {
+				return false
+			}
+			return s.store.SetReleasedAt(r.ID, at) == nil
+		}, nil
+	})
+}
+
+// evaluateActivity updates the activity system tags of all
+// the repositories, the tags set by the user are not changed.
+// The activity is evaluated from the pushed_at, released_at and
+// archived stored by the last sync or import, nothing is fetched.
+// It returns how many repositories changed.
+func (s *server) evaluateActivity() (int, error) {
+	repos, err := s.store.GetReposByTag("")
+	if err != nil {
+		return 0, err
+	}
+	activity := s.rules.Rules().Activity
+	changed := 0
+	for _, r := range repos {
+		tags := repo.Tags(activity.Suggest(r))
+		if repo.NamesEq(tags, r.SystemTags) {
+			continue
+		}
+		if err := s.store.SetSystemTags(r.ID, tags); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// watchActivity evaluates the activity now and every interval,
+// the repositories become stale without any import, even if
+// they had pushes since their last sync.
+func (s *server) watchActivity(interval time.Duration) {
+	evaluate := func() {
+		n, err := s.evaluateActivity()
+		if err != nil {
+			log.Printf("failed to evaluate activity: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("activity of %d repositories changed", n)
+		}
+	}
+	evaluate()
+	for range time.Tick(interval) {
+		evaluate()
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+// feedback accepts or rejects a suggestion with POST
+// /feedback/{id}/accept or /feedback/{id}/reject, lists the
+// feedback to a repository with GET /feedback/{id} and
+// reports the precision of the rules with GET
+// /feedback/precision.
+func (s *server) feedback(w http.ResponseWriter, r *http.Request) {
+	path, action := r.URL.Path[len("/feedback/"):], ""
+	if path == "precision" {
+		if r.Method != "GET" {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+		s.precision(w, r)
+		return
+	}
+	if i := strings.Index(path, "/"); i >= 0 {
+		path, action = path[:i], path[i+1:]
+	}
+	id, err := strconv.Atoi(path)
+	if err != nil {
+		http.Error(w, http.StatusText(400), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+		s.getFeedback(w, id)
+	case (action == "accept" || action == "reject") && r.Method == "POST":
+		s.setFeedback(w, r, id, action == "accept")
+	case action == "" || action == "accept" || action == "reject":
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, http.StatusText(404), http.StatusNotFound)
+	}
+}
+
+// setFeedback records the feedback to the suggestion of the
+// tag and adds it to the repository tags if it is accepted,
+// or removes it if it is rejected.
+func (s *server) setFeedback(w http.ResponseWriter, r *http.Request, id int, accepted bool) {
+	tag := strings.TrimSpace(r.FormValue("tag"))
+	if tag == "" {
+		http.Error(w, "missing tag", http.StatusBadRequest)
+		return
+	}
+
+	repository, err := s.store.GetRepo(id)
+	if err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+			return
+		}
+		log.Println(err)
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	f := &storage.Feedback{RepoID: id, Tag: tag, Rule: r.FormValue("rule"), Accepted: accepted}
+	if f.Rule == "" {
+		f.Rule = s.ruleOf(repository, tag)
+	}
+
+	// the activity tags are system tags, only the feedback
+	// to them is recorded.
+	if f.Rule != repo.ActivityRule {
+		tags := make([]string, 0, len(repository.Tags)+1)
+		for _, t := range repository.Tags {
+			if t != tag {
+				tags = append(tags, t)
+			}
+		}
+		if accepted {
+			tags = append(tags, tag)
+		}
+		if len(tags) != len(repository.Tags) {
+			repository.SetTags(tags...)
+			if err := s.store.UpdateTags(repository); err != nil {
+				http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	if err := s.store.SetFeedback(f); err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(f)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// ruleOf returns the rule of the best suggestion of
+// tag to repository, or empty if it is not suggested.
+func (s *server) ruleOf(repository *repo.Repo, tag string) string {
+	opts := repo.RankOptions{KeepApplied: true}
+	for _, sug := range repo.Rank(repository, s.suggestionsOf(repository), opts) {
+		if sug.Tag == tag {
+			return sug.Rule
+		}
+	}
+	return ""
+}
+
+// rejected returns the tags rejected to the repository id.
+func (s *server) rejected(id int) []string {
+	feedback, err := s.store.GetFeedback(id)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	tags := make([]string, 0)
+	for _, f := range feedback {
+		if !f.Accepted {
+			tags = append(tags, f.Tag)
+		}
+	}
+	return tags
+}
+
+func (s *server) getFeedback(w http.ResponseWriter, id int) {
+	feedback, err := s.store.GetFeedback(id)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(feedback)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) precision(w http.ResponseWriter, r *http.Request) {
+	ps, err := s.store.GetPrecision()
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+	ps = withRules(ps, s.rules.Rules())
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(ps)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+	}
+}
+
+// withRules adds to ps the rules of rs without feedback,
+// sorted by rule.
+func withRules(ps []*storage.Precision, rs *repo.Rules) []*storage.Precision {
+	has := make(map[string]bool, len(ps))
+	for _, p := range ps {
+		has[p.Rule] = true
+	}
+	for _, rule := range rs.Rules {
+		name := "rules/" + rule.Name
+		if !has[name] {
+			has[name] = true
+			ps = append(ps, storage.NewPrecision(name, 0, 0))
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].Rule < ps[j].Rule
+	})
+	return ps
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+func TestSetFeedback(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	for id := 1; id <= 2; id++ {
+		r := &repo.Repo{ID: id, Name: "a", FullName: "x/a", Stars: 20000, Tags: []string{"go"}}
+		if err := s.store.InsertRepo(r); err != nil {
+			t.Fatalf("failed to insert repo: %v", err)
+		}
+	}
+
+	tt := []struct {
+		url  string
+		code int
+		// the expected feedback.
+		feedback storage.Feedback
+	}{
+		{"/feedback/1/accept?tag=very-popular", http.StatusCreated,
+			storage.Feedback{RepoID: 1, Tag: "very-popular", Rule: "rules/very-popular", Accepted: true}},
+		{"/feedback/1/reject?tag=go&rule=manual", http.StatusCreated,
+			storage.Feedback{RepoID: 1, Tag: "go", Rule: "manual"}},
+		{"/feedback/2/reject?tag=very-popular", http.StatusCreated,
+			storage.Feedback{RepoID: 2, Tag: "very-popular", Rule: "rules/very-popular"}},
+		// the activity tags are not user tags.
+		{"/feedback/2/accept?tag=active&rule=activity", http.StatusCreated,
+			storage.Feedback{RepoID: 2, Tag: "active", Rule: "activity", Accepted: true}},
+		{"/feedback/1/accept", http.StatusBadRequest, storage.Feedback{}},
+		{"/feedback/3/accept?tag=go", http.StatusNotFound, storage.Feedback{}},
+	}
+	for _, tc := range tt {
+		w := httptest.NewRecorder()
+		s.feedback(w, httptest.NewRequest("POST", tc.url, nil))
+		if w.Code != tc.code {
+			t.Fatalf("expected status %d to %s; got %d: %s", tc.code, tc.url, w.Code, w.Body)
+		}
+		if w.Code != http.StatusCreated {
+			continue
+		}
+		f := storage.Feedback{}
+		if err := json.NewDecoder(w.Body).Decode(&f); err != nil {
+			t.Fatalf("failed to decode feedback: %v", err)
+		}
+		f.CreatedAt = tc.feedback.CreatedAt
+		if f != tc.feedback {
+			t.Errorf("expected feedback %+v; got %+v", tc.feedback, f)
+		}
+	}
+
+	r, err := s.store.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if want := []string{"very-popular"}; !reflect.DeepEqual(r.Tags, want) {
+		t.Fatalf("expected tags %v; got %v", want, r.Tags)
+	}
+	r, err = s.store.GetRepo(2)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if want := []string{"go"}; !reflect.DeepEqual(r.Tags, want) {
+		t.Fatalf("expected tags %v; got %v", want, r.Tags)
+	}
+	if rejected := s.rejected(1); !reflect.DeepEqual(rejected, []string{"go"}) {
+		t.Fatalf("expected go rejected; got %v", rejected)
+	}
+
+	w := httptest.NewRecorder()
+	s.feedback(w, httptest.NewRequest("GET", "/feedback/1", nil))
+	var feedback []*storage.Feedback
+	if err := json.NewDecoder(w.Body).Decode(&feedback); err != nil {
+		t.Fatalf("failed to decode feedback: %v", err)
+	}
+	if len(feedback) != 2 || feedback[0].Tag != "go" || feedback[1].Tag != "very-popular" {
+		t.Fatalf("got wrong feedback: %+v", feedback)
+	}
+
+	w = httptest.NewRecorder()
+	s.feedback(w, httptest.NewRequest("GET", "/feedback/precision", nil))
+	var ps []storage.Precision
+	if err := json.NewDecoder(w.Body).Decode(&ps); err != nil {
+		t.Fatalf("failed to decode precision: %v", err)
+	}
+	// the default rules without feedback have precision 0.
+	expected := []storage.Precision{
+		{Rule: "activity", Accepted: 1, Precision: 1},
+		{Rule: "manual", Rejected: 1},
+		{Rule: "rules/license"},
+		{Rule: "rules/not-popular"},
+		{Rule: "rules/owner"},
+		{Rule: "rules/popular"},
+		{Rule: "rules/very-popular", Accepted: 1, Rejected: 1, Precision: 0.5},
+	}
+	if !reflect.DeepEqual(ps, expected) {
+		t.Fatalf("expected precision %+v; got %+v", expected, ps)
+	}
+}
+
+func TestWithRules(t *testing.T) {
+	rs, err := repo.ParseRules([]byte(`{"rules": [{"tag": "cli"}, {"name": "web", "tag": "http"}]}`))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	ps := []*storage.Precision{
+		storage.NewPrecision("rules/web", 3, 1),
+		storage.NewPrecision("readme", 0, 2),
+	}
+	got := withRules(ps, rs)
+
+	expected := []storage.Precision{
+		{Rule: "readme", Rejected: 2},
+		{Rule: "rules/cli"},
+		{Rule: "rules/web", Accepted: 3, Rejected: 1, Precision: 0.75},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d rules; got %d", len(expected), len(got))
+	}
+	for i, p := range got {
+		if *p != expected[i] {
+			t.Errorf("expected %+v; got %+v", expected[i], p)
+		}
+	}
+}
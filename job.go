@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+const (
+	// jobWorkers is the number of repositories suggested at once.
+	jobWorkers = 4
+	// jobUpdateEvery is how many repositories are processed
+	// between the updates of the job progress.
+	jobUpdateEvery = 10
+	// defaultThreshold is the default minimum score of the
+	// suggestions applied by a job.
+	defaultThreshold = 0.9
+)
+
+// jobs starts a job with POST /jobs and reports
+// its progress with GET /jobs/{id}.
+func (s *server) jobs(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/jobs":
+		s.startJob(w, r)
+	case r.Method == "GET" && len(r.URL.Path) > len("/jobs/"):
+		s.getJob(w, r)
+	default:
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) startJob(w http.ResponseWriter, r *http.Request) {
+	j := &storage.Job{
+		Status:    storage.JobRunning,
+		DryRun:    r.FormValue("dry_run") == "true",
+		Threshold: defaultThreshold,
+		Report:    make([]*storage.JobResult, 0),
+	}
+	if v := r.FormValue("threshold"); v != "" {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil || threshold < 0 || threshold > 1 {
+			http.Error(w, fmt.Sprintf("invalid threshold %q", v), http.StatusBadRequest)
+			return
+		}
+		j.Threshold = threshold
+	}
+
+	// the untagged repositories, or all the ones that match the filter.
+	repos, err := s.store.GetReposByTagFrom(r.FormValue("tag"), r.FormValue("origin"))
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+	if r.FormValue("all") != "true" {
+		untagged := make([]*repo.Repo, 0, len(repos))
+		for _, repository := range repos {
+			if len(repository.Tags) == 0 {
+				untagged = append(untagged, repository)
+			}
+		}
+		repos = untagged
+	}
+	j.Total = len(repos)
+
+	if err := s.store.CreateJob(j); err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+	// encoded before the job changes.
+	body, err := json.Marshal(j)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+	go s.runJob(j, repos)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+strconv.Itoa(j.ID))
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := w.Write(body); err != nil {
+		log.Println(err)
+	}
+}
+
+// runJob suggests tags to repos and applies the ones
+// above the threshold of j, unless it is a dry run.
+func (s *server) runJob(j *storage.Job, repos []*repo.Repo) {
+	repoCh := make(chan *repo.Repo)
+	resultCh := make(chan *storage.JobResult)
+	done := make(chan struct{})
+	for i := 0; i < jobWorkers; i++ {
+		go func() {
+			for repository := range repoCh {
+				opts := repo.RankOptions{Rejected: s.rejected(repository.ID)}
+				resultCh <- &storage.JobResult{
+					RepoID:      repository.ID,
+					FullName:    repository.FullName,
+					Suggestions: repo.Rank(repository, s.suggestionsOf(repository), opts),
+				}
+			}
+			done <- struct{}{}
+		}()
+	}
+	go func() {
+		for _, repository := range repos {
+			repoCh <- repository
+		}
+		close(repoCh)
+		for i := 0; i < jobWorkers; i++ {
+			<-done
+		}
+		close(resultCh)
+	}()
+
+	byID := make(map[int]*repo.Repo, len(repos))
+	for _, repository := range repos {
+		byID[repository.ID] = repository
+	}
+	// the tags are applied by one goroutine.
+	for result := range resultCh {
+		if !j.DryRun {
+			s.applyResult(byID[result.RepoID], result, j.Threshold)
+			j.Applied += len(result.Applied)
+		}
+		j.Report = append(j.Report, result)
+		j.Done++
+		if j.Done%jobUpdateEvery == 0 {
+			if err := s.store.UpdateJob(j); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	j.Status = storage.JobDone
+	if err := s.store.UpdateJob(j); err != nil {
+		log.Println(err)
+	}
+}
+
+// applyResult adds to repository the suggested tags with
+// score at least threshold, but the activity tags.
+func (s *server) applyResult(repository *repo.Repo, result *storage.JobResult, threshold float64) {
+	for _, sug := range result.Suggestions {
+		if sug.Score >= threshold && sug.Rule != repo.ActivityRule {
+			result.Applied = append(result.Applied, sug.Tag)
+		}
+	}
+	if len(result.Applied) == 0 {
+		return
+	}
+
+	// the tags applied by jobs are not learned.
+	store := s.store
+	if ls, ok := store.(learningStore); ok {
+		store = ls.Storage
+	}
+	// the tags may have changed since the job read them.
+	current, err := store.GetRepo(repository.ID)
+	if err == nil {
+		current.SetTags(append(current.Tags, result.Applied...)...)
+		err = store.UpdateTags(current)
+	}
+	if err != nil {
+		result.Applied = nil
+		result.Error = err.Error()
+	}
+}
+
+func (s *server) getJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Path[len("/jobs/"):])
+	if err != nil {
+		http.Error(w, http.StatusText(400), http.StatusBadRequest)
+		return
+	}
+
+	j, err := s.store.GetJob(id)
+	if err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+			return
+		}
+		log.Println(err)
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(j)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+	}
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rschio/repoTagger/repo"
 	"github.com/rschio/repoTagger/storage"
@@ -16,6 +24,283 @@ import (
 
 type server struct {
 	store storage.Storage
+	// githubURL is the URL of the GitHub API.
+	githubURL string
+	// token is the default GitHub token, it can be
+	// overridden per request by the X-GitHub-Token header.
+	token string
+	// rateLimit is the rate limit of the default token,
+	// shared by all the requests.
+	rateLimit repo.RateLimit
+	// gitlab is the GitLab instance, its token can be
+	// overridden per request by the X-GitLab-Token header.
+	gitlab repo.GitLab
+	// gitea is the Gitea or Forgejo instance, its token can
+	// be overridden per request by the X-Gitea-Token header.
+	gitea repo.Gitea
+	// seedTags is the default of adding the topics to
+	// the tags of new repositories, it can be overridden
+	// per request.
+	seedTags bool
+	// fetchReadme is the default of fetching the READMEs of
+	// the imported repositories, it can be overridden per
+	// request.
+	fetchReadme bool
+	// fetchRelease is the default of fetching the latest
+	// release of the imported repositories, it can be
+	// overridden per request.
+	fetchRelease bool
+	// rules suggest the tags of the repositories.
+	rules *rulesFile
+	// learner suggests the tags given to similar repositories.
+	learner *repo.Learner
+	// keywords suggests the keywords of the descriptions.
+	keywords *repo.KeywordIndex
+	// unstarPolicy is the default policy to the repositories
+	// no longer starred, it can be overridden per request.
+	unstarPolicy storage.UnstarPolicy
+}
+
+// github returns a GitHub client authenticated with the token
+// forwarded by the request or with the server default token.
+// The forwarded tokens are not kept, their clients have their
+// own rate limit.
+func (s *server) github(r *http.Request) *repo.GitHub {
+	if token := r.Header.Get("X-GitHub-Token"); token != "" {
+		return &repo.GitHub{BaseURL: s.githubURL, Token: token}
+	}
+	return &repo.GitHub{BaseURL: s.githubURL, Token: s.token, RateLimit: &s.rateLimit}
+}
+
+// rateLimited answers 429 if err is repo.RateLimitErr,
+// with Retry-After set to the time left to the reset.
+func rateLimited(w http.ResponseWriter, err error) bool {
+	rlErr, ok := err.(repo.RateLimitErr)
+	if !ok {
+		return false
+	}
+	retry := int(math.Ceil(time.Duration(rlErr).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// source returns the Source named name.
+func (s *server) source(r *http.Request, name string) (repo.Source, error) {
+	switch name {
+	case "", repo.SourceGitHub:
+		return s.github(r), nil
+	case repo.SourceGitLab:
+		gl := s.gitlab
+		if token := r.Header.Get("X-GitLab-Token"); token != "" {
+			gl.Token = token
+		}
+		return &gl, nil
+	case repo.SourceGitea:
+		if s.gitea.BaseURL == "" {
+			return nil, fmt.Errorf("gitea source is not configured")
+		}
+		gt := s.gitea
+		if token := r.Header.Get("X-Gitea-Token"); token != "" {
+			gt.Token = token
+		}
+		return &gt, nil
+	}
+	return nil, fmt.Errorf("unknown source %q", name)
+}
+
+// account identifies user of src in the storage, GitHub
+// users keep the name used before other sources.
+func account(src repo.Source, user string) string {
+	if src.Name() == repo.SourceGitHub {
+		return user
+	}
+	return src.Name() + ":" + user
+}
+
+// githubToken reads the default GitHub token from the env
+// REPOTAGGER_GITHUB_TOKEN or from the file in the env
+// REPOTAGGER_GITHUB_TOKEN_FILE.
+func githubToken() string {
+	if token := os.Getenv("REPOTAGGER_GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	path := os.Getenv("REPOTAGGER_GITHUB_TOKEN_FILE")
+	if path == "" {
+		return ""
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Printf("failed to read token file %s: %v", path, err)
+		return ""
+	}
+	return strings.TrimSpace(string(bs))
+}
+
+// importResult is the response of a repositories import.
+type importResult struct {
+	// Imported is the number of repositories stored.
+	Imported int `json:"imported"`
+	*storage.UpsertCount
+
+	FailedPages []failedPage `json:"failed_pages,omitempty"`
+	// Unstarred are the ids of the repositories
+	// no longer starred by the user.
+	Unstarred []int `json:"unstarred,omitempty"`
+	// Readmes is the number of READMEs stored or
+	// changed since the last import.
+	Readmes int `json:"readmes,omitempty"`
+	// Releases is the number of latest releases
+	// stored or changed since the last import.
+	Releases int `json:"releases,omitempty"`
+}
+
+// minLearnedScore is the minimum score of the tags
+// suggested by the learner.
+const minLearnedScore = 0.5
+
+// learningStore is a storage that teaches the learner the
+// tags set with UpdateTags, indexes the descriptions of the
+// repositories stored with UpsertRepo and forgets and removes
+// from the index the ones deleted by Unstar.
+type learningStore struct {
+	storage.Storage
+	learner  *repo.Learner
+	keywords *repo.KeywordIndex
+}
+
+func (s learningStore) UpdateTags(r *repo.Repo) error {
+	if err := s.Storage.UpdateTags(r); err != nil {
+		return err
+	}
+	s.learner.Learn(r)
+	return nil
+}
+
+func (s learningStore) UpsertRepo(r *repo.Repo) (storage.UpsertResult, error) {
+	res, err := s.Storage.UpsertRepo(r)
+	if err != nil {
+		return res, err
+	}
+	s.keywords.Add(r)
+	return res, nil
+}
+
+func (s learningStore) Unstar(user string, starred []int, policy storage.UnstarPolicy) ([]int, error) {
+	ids, err := s.Storage.Unstar(user, starred, policy)
+	if err != nil || policy != storage.Delete {
+		return ids, err
+	}
+	// the repos of other users are not deleted.
+	for _, id := range ids {
+		_, err := s.Storage.GetRepo(id)
+		if err != nil && err.Error() == sql.ErrNoRows.Error() {
+			s.learner.Forget(id)
+			s.keywords.Remove(id)
+		}
+	}
+	return ids, nil
+}
+
+// train teaches learner the tags and indexes in keywords the
+// descriptions of all the repositories of store.
+func train(learner *repo.Learner, keywords *repo.KeywordIndex, store storage.Storage) error {
+	repos, err := store.GetReposByTag("")
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		learner.Learn(r)
+		keywords.Add(r)
+	}
+	return nil
+}
+
+// fetchWorkers is the number of repositories fetched at once.
+const fetchWorkers = 8
+
+// fetchEach calls fetch with each of repos from fetchWorkers
+// goroutines, what is the name of the fetched data in the logs.
+// The functions returned by fetch store the data and report
+// whether it changed, they are called by one goroutine since
+// the storage is written by one goroutine. It returns how many
+// repositories changed, a repository not found is skipped.
+func fetchEach(ctx context.Context, repos []*repo.Repo, what string,
+	fetch func(r *repo.Repo) (store func() bool, err error)) int {
+	repoCh := make(chan *repo.Repo)
+	storeCh := make(chan func() bool)
+	var wg sync.WaitGroup
+	for i := 0; i < fetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range repoCh {
+				store, err := fetch(r)
+				if err != nil {
+					if _, ok := err.(repo.NotFoundErr); !ok {
+						log.Printf("failed to fetch %s of %s: %v", what, r.FullName, err)
+					}
+					continue
+				}
+				storeCh <- store
+			}
+		}()
+	}
+	go func() {
+		defer close(repoCh)
+		for _, r := range repos {
+			select {
+			case repoCh <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(storeCh)
+	}()
+
+	changed := 0
+	for store := range storeCh {
+		if store() {
+			changed++
+		}
+	}
+	return changed
+}
+
+// fetchReadmes fetches the READMEs of repos and stores the ones
+// that changed, it returns how many were stored. The stored READMEs
+// are sent to src so the unchanged ones are not downloaded.
+func (s *server) fetchReadmes(ctx context.Context, src repo.ReadmeFetcher, repos []*repo.Repo) int {
+	olds := make(map[int]*repo.Readme, len(repos))
+	for _, r := range repos {
+		old, err := s.store.GetReadme(r.ID)
+		if err != nil {
+			continue
+		}
+		olds[r.ID] = old
+	}
+
+	return fetchEach(ctx, repos, "readme", func(r *repo.Repo) (func() bool, error) {
+		old := olds[r.ID]
+		readme, err := src.Readme(ctx, r, old)
+		if err != nil {
+			return nil, err
+		}
+		return func() bool {
+			if old != nil && old.SHA == readme.SHA && old.ETag == readme.ETag {
+				return false
+			}
+			return s.store.SetReadme(r.ID, readme) == nil
+		}, nil
+	})
+}
+
+type failedPage struct {
+	Page  int    `json:"page"`
+	Error string `json:"error"`
 }
 
 func (s *server) getRepos(w http.ResponseWriter, r *http.Request) {
@@ -25,21 +310,251 @@ func (s *server) getRepos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := r.URL.Path[len("/repos/"):]
-	repos, err := repo.GetGithubRepos(user)
+	policy := s.unstarPolicy
+	if p := r.FormValue("unstarred"); p != "" {
+		var err error
+		policy, err = storage.ParseUnstarPolicy(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	src, err := s.source(r, r.FormValue("source"))
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	collection := repo.CollectionStarred
+	if c := r.FormValue("collection"); c != "" {
+		collection = c
+	}
+	switch collection {
+	case repo.CollectionStarred, repo.CollectionOwned, repo.CollectionWatched, repo.CollectionOrg:
+	default:
+		http.Error(w, fmt.Sprintf("unknown collection %q", collection), http.StatusBadRequest)
+		return
+	}
+	gh, isGitHub := src.(*repo.GitHub)
+	if isGitHub {
+		gh.Progress = func(done, total int) {
+			log.Printf("fetching %s repos of %s: %d/%d pages", collection, user, done, total)
+		}
+	}
+	// only the stars can come from other sources.
+	stars := collection == repo.CollectionStarred
+	if !stars && !isGitHub {
+		http.Error(w, fmt.Sprintf("collection %s is only in github", collection),
+			http.StatusBadRequest)
+		return
+	}
+	acc := account(src, user)
+	state, err := s.store.GetSyncState(acc)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	// the first import or a forced one fetches all the pages,
+	// the next ones only fetch the stars after the last sync.
+	// Other collections are always fetched in full.
+	var repos []*repo.Repo
+	newState := &repo.SyncState{User: acc}
+	syncer, canSync := src.(repo.Syncer)
+	full := !canSync || state.LastStarredAt.IsZero() || r.FormValue("full") == "true"
+	if !stars {
+		full = true
+		repos, err = gh.Collection(r.Context(), collection, user)
+	} else if full {
+		repos, err = src.Starred(r.Context(), user)
+		newState.Update(repos)
+	} else {
+		repos, newState, err = syncer.SyncContext(r.Context(), user, state)
+	}
+	pErr, partial := err.(repo.PartialErr)
+	if err != nil && !partial {
 		if _, ok := err.(repo.NotFoundErr); ok {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if rateLimited(w, err) {
+			return
+		}
 		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
 		return
 	}
 
-	for _, repository := range repos {
-		s.store.InsertRepo(repository)
+	seed := s.seedTags
+	if v := r.FormValue("seed_tags"); v != "" {
+		seed = v == "true"
+	}
+	if seed {
+		for _, repository := range repos {
+			repository.SeedTags()
+		}
+	}
+
+	count, stored := storage.UpsertRepos(s.store, repos)
+	result := &importResult{Imported: len(stored), UpsertCount: count}
+	if err := s.store.SetCollection(acc, collection, repoIDs(stored)); err != nil {
+		log.Println(err)
+	}
+
+	// a partial import is not recorded so the
+	// missing pages are fetched by the next sync.
+	if stars && !partial {
+		if err := s.store.SetSyncState(newState); err != nil {
+			log.Println(err)
+		}
+	}
+
+	// only a complete list of stars shows which repositories
+	// were unstarred, the ones not stored are still starred.
+	if stars && full && !partial {
+		result.Unstarred, err = s.store.Unstar(acc, repoIDs(repos), policy)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	fetchReadme := s.fetchReadme
+	if v := r.FormValue("readme"); v != "" {
+		fetchReadme = v == "true"
+	}
+	if rf, ok := src.(repo.ReadmeFetcher); ok && fetchReadme {
+		result.Readmes = s.fetchReadmes(r.Context(), rf, repos)
+	}
+	fetchRelease := s.fetchRelease
+	if v := r.FormValue("releases"); v != "" {
+		fetchRelease = v == "true"
+	}
+	if rf, ok := src.(repo.ReleaseFetcher); ok && fetchRelease {
+		result.Releases = s.fetchReleases(r.Context(), rf, repos)
+	}
+
+	status := http.StatusCreated
+	if partial {
+		// some pages are missing, report which ones.
+		status = http.StatusMultiStatus
+		for _, e := range pErr {
+			result.FailedPages = append(result.FailedPages,
+				failedPage{Page: e.Page, Error: e.Err.Error()})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// repoIDs returns the ids of repos.
+func repoIDs(repos []*repo.Repo) []int {
+	ids := make([]int, len(repos))
+	for i, repository := range repos {
+		ids[i] = repository.ID
+	}
+	return ids
+}
+
+// maxImportSize is the largest export file accepted by
+// importFile, a variable so tests can lower it.
+var maxImportSize int64 = 32 << 20
+
+// exportResult is the response of an import from an export file.
+type exportResult struct {
+	*storage.UpsertCount
+	Repos []storage.RepoResult `json:"repos"`
+}
+
+// importExport stores the repositories of the export read from rd,
+// they are set as starred by user if user is not empty.
+func importExport(store storage.Storage, rd io.Reader, user string, seed bool) (*exportResult, error) {
+	repos, err := repo.ReadExport(rd)
+	if err != nil {
+		return nil, err
+	}
+	if seed {
+		for _, repository := range repos {
+			repository.SeedTags()
+		}
+	}
+
+	result := &exportResult{}
+	var stored []*repo.Repo
+	result.Repos, result.UpsertCount, stored = storage.UpsertReport(store, repos)
+	if user != "" {
+		if err := store.SetStarred(user, repoIDs(stored)); err != nil {
+			log.Println(err)
+		}
+	}
+	return result, nil
+}
+
+// importFile imports the export file sent in the body.
+func (s *server) importFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// the body is the export file, not a form.
+	query := r.URL.Query()
+	seed := s.seedTags
+	if v := query.Get("seed_tags"); v != "" {
+		seed = v == "true"
+	}
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	result, err := importExport(s.store, body, query.Get("user"), seed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// importCmd runs the command import, it imports an export file,
+// or the standard input if the file is -, without the server.
+func importCmd(store storage.Storage, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	user := fs.String("user", "", "set the repositories as starred by `user`")
+	seed := fs.Bool("seed-tags", os.Getenv("REPOTAGGER_SEED_TAGS") == "true",
+		"add the topics to the tags of new repositories")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: repoTagger import [flags] file")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	rd := io.Reader(os.Stdin)
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rd = f
+	}
+
+	result, err := importExport(store, rd, *user, *seed)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
 }
 
 func (s *server) search(w http.ResponseWriter, r *http.Request) {
@@ -49,7 +564,41 @@ func (s *server) search(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tag := r.URL.Path[len("/search/"):]
-	repos, err := s.store.GetReposByTag(tag)
+	repos, err := s.store.GetReposByTagFrom(tag, r.FormValue("origin"))
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	if len(repos) == 0 {
+		http.Error(w, http.StatusText(404), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(repos)
+	if err != nil {
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+	}
+}
+
+// searchText searches the repositories by the text of their README.
+func (s *server) searchText(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.FormValue("q")
+	if q == "" {
+		http.Error(w, "missing query q", http.StatusBadRequest)
+		return
+	}
+	repos, err := s.store.SearchText(q)
+	if _, ok := err.(storage.QueryErr); ok {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
 		return
@@ -73,11 +622,27 @@ func (s *server) suggest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := strconv.Atoi(r.URL.Path[len("/suggest/"):])
+	opts, err := rankOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the path is /suggest/{id}, /suggest/{id}/manifests
+	// or /suggest/{id}/learned.
+	path, sub := r.URL.Path[len("/suggest/"):], ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		path, sub = path[:i], path[i+1:]
+	}
+	id, err := strconv.Atoi(path)
 	if err != nil {
 		http.Error(w, http.StatusText(400), http.StatusBadRequest)
 		return
 	}
+	if sub != "" && sub != "manifests" && sub != "learned" {
+		http.Error(w, http.StatusText(404), http.StatusNotFound)
+		return
+	}
 
 	repository, err := s.store.GetRepo(id)
 	if err != nil {
@@ -89,22 +654,178 @@ func (s *server) suggest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
 		return
 	}
+	opts.Rejected = s.rejected(repository.ID)
+
+	switch sub {
+	case "manifests":
+		s.suggestManifests(w, r, repository, opts)
+		return
+	case "learned":
+		writeSuggestions(w, repository, s.learner.Suggest(repository, minLearnedScore), opts)
+		return
+	}
 
-	repoName := repository.URLHTTP[len("https://github.com/"):]
-	suggestion, err := repo.Suggest(repoName)
+	// repos stored by older versions have no
+	// metadata, fetch it once from the source.
+	if repository.CreatedAt.IsZero() {
+		repository, err = s.refresh(r, repository)
+		if rateLimited(w, err) {
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+			return
+		}
+	}
+	writeSuggestions(w, repository, s.suggestionsOf(repository), opts)
+}
+
+// suggestionsOf returns the suggestions of the rules, the
+// activity, the stored README, the description keywords and
+// the learner to repository.
+func (s *server) suggestionsOf(repository *repo.Repo) []repo.Suggestion {
+	rules := s.rules.Rules()
+	suggestions := append(rules.Suggest(repository), rules.Activity.Suggest(repository)...)
+	readme, err := s.store.GetReadme(repository.ID)
 	if err != nil {
 		log.Println(err)
+	}
+	if readme != nil {
+		suggestions = append(suggestions, repo.SuggestReadme(readme.Text)...)
+	}
+	suggestions = append(suggestions, s.keywords.Suggest(repository)...)
+	return append(suggestions, s.learner.Suggest(repository, minLearnedScore)...)
+}
+
+// rankOptions reads the query parameters min_score,
+// limit and applied of r.
+func rankOptions(r *http.Request) (repo.RankOptions, error) {
+	opts := repo.RankOptions{KeepApplied: r.FormValue("applied") == "true"}
+	if v := r.FormValue("min_score"); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil || score < 0 || score > 1 {
+			return opts, fmt.Errorf("invalid min_score %q", v)
+		}
+		opts.MinScore = score
+	}
+	if v := r.FormValue("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return opts, fmt.Errorf("invalid limit %q", v)
+		}
+		opts.Limit = limit
+	}
+	return opts, nil
+}
+
+// writeSuggestions writes the suggestions to repository
+// ranked with opts.
+func writeSuggestions(w http.ResponseWriter, repository *repo.Repo, suggestions []repo.Suggestion,
+	opts repo.RankOptions) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(repo.Rank(repository, suggestions, opts))
+	if err != nil {
 		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+	}
+}
+
+// testRules evaluates the rules against the repository sent in
+// the body, or the stored repository id.
+func (s *server) testRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+		return
+	}
+
+	repository := &repo.Repo{}
+	if v := r.URL.Query().Get("id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, http.StatusText(400), http.StatusBadRequest)
+			return
+		}
+		repository, err = s.store.GetRepo(id)
+		if err != nil {
+			if err.Error() == sql.ErrNoRows.Error() {
+				http.Error(w, http.StatusText(404), http.StatusNotFound)
+				return
+			}
+			log.Println(err)
+			http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(repository); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(suggestion)
+	err := json.NewEncoder(w).Encode(s.rules.Rules().Suggest(repository))
 	if err != nil {
 		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
 	}
 }
 
+// suggestManifests suggests tags to repository from
+// its manifest files, like go.mod or package.json.
+func (s *server) suggestManifests(w http.ResponseWriter, r *http.Request, repository *repo.Repo,
+	opts repo.RankOptions) {
+	src, err := s.source(r, repository.Source)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+	mf, ok := src.(repo.ManifestFetcher)
+	if !ok {
+		http.Error(w, fmt.Sprintf("source %s has no manifests", src.Name()),
+			http.StatusNotImplemented)
+		return
+	}
+	manifests, err := mf.Manifests(r.Context(), repository)
+	if mErr, ok := err.(repo.ManifestErr); ok {
+		// the manifests that failed are not needed by the others.
+		log.Printf("manifests of %s: %v", repository.FullName, mErr)
+		err = nil
+	}
+	if rateLimited(w, err) {
+		return
+	}
+	if err != nil {
+		if _, ok := err.(repo.NotFoundErr); ok {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Println(err)
+		http.Error(w, http.StatusText(500), http.StatusInternalServerError)
+		return
+	}
+
+	suggestions := make([]repo.Suggestion, 0)
+	for _, m := range manifests {
+		suggestions = append(suggestions, repo.SuggestManifest(m)...)
+	}
+	writeSuggestions(w, repository, suggestions, opts)
+}
+
+// refresh fetches the metadata of repository from
+// its source and stores it.
+func (s *server) refresh(r *http.Request, repository *repo.Repo) (*repo.Repo, error) {
+	src, err := s.source(r, repository.Source)
+	if err != nil {
+		return nil, err
+	}
+	details, err := src.Details(r.Context(), repository)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.store.UpsertRepo(details); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
 func (s *server) setTag(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "PUT" {
 		http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
@@ -150,7 +871,75 @@ func main() {
 	if err != nil {
 		panic("failed to connect to db")
 	}
-	s := &server{store: db}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := importCmd(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// the jobs of the last run never finish.
+	n, err := db.FailRunningJobs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if n > 0 {
+		log.Printf("marked %d interrupted jobs as failed", n)
+	}
+	policy := storage.Mark
+	if p := os.Getenv("REPOTAGGER_UNSTAR_POLICY"); p != "" {
+		policy, err = storage.ParseUnstarPolicy(p)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	gitlabURL := os.Getenv("REPOTAGGER_GITLAB_URL")
+	if gitlabURL == "" {
+		gitlabURL = "https://gitlab.com"
+	}
+	gitlab := repo.GitLab{
+		BaseURL: strings.TrimRight(gitlabURL, "/"),
+		Token:   os.Getenv("REPOTAGGER_GITLAB_TOKEN"),
+	}
+
+	gitea := repo.Gitea{
+		BaseURL: strings.TrimRight(os.Getenv("REPOTAGGER_GITEA_URL"), "/"),
+		Token:   os.Getenv("REPOTAGGER_GITEA_TOKEN"),
+	}
+
+	rules, err := loadRules(os.Getenv("REPOTAGGER_RULES"))
+	if err != nil {
+		log.Fatalf("failed to load rules: %v", err)
+	}
+	go rules.watch(5 * time.Second)
+
+	learner, keywords := repo.NewLearner(), repo.NewKeywordIndex()
+	if err := train(learner, keywords, db); err != nil {
+		log.Fatalf("failed to train the learner: %v", err)
+	}
+
+	s := &server{
+		store:        learningStore{Storage: db, learner: learner, keywords: keywords},
+		learner:      learner,
+		keywords:     keywords,
+		githubURL:    os.Getenv("REPOTAGGER_GITHUB_URL"),
+		token:        githubToken(),
+		gitlab:       gitlab,
+		gitea:        gitea,
+		seedTags:     os.Getenv("REPOTAGGER_SEED_TAGS") == "true",
+		fetchReadme:  os.Getenv("REPOTAGGER_FETCH_README") == "true",
+		fetchRelease: os.Getenv("REPOTAGGER_FETCH_RELEASES") == "true",
+		rules:        rules,
+		unstarPolicy: policy,
+	}
+
+	interval := 24 * time.Hour
+	if v := os.Getenv("REPOTAGGER_ACTIVITY_INTERVAL"); v != "" {
+		interval, err = time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			log.Fatalf("invalid activity interval %q", v)
+		}
+	}
+	go s.watchActivity(interval)
 
 	port := os.Getenv("REPOTAGGER_PORT")
 	if port == "" {
@@ -158,8 +947,14 @@ func main() {
 	}
 
 	http.HandleFunc("/repos/", s.getRepos)
+	http.HandleFunc("/import", s.importFile)
 	http.HandleFunc("/search/", s.search)
+	http.HandleFunc("/search", s.searchText)
 	http.HandleFunc("/suggest/", s.suggest)
 	http.HandleFunc("/tag/", s.setTag)
+	http.HandleFunc("/rules/test", s.testRules)
+	http.HandleFunc("/jobs", s.jobs)
+	http.HandleFunc("/jobs/", s.jobs)
+	http.HandleFunc("/feedback/", s.feedback)
 	http.ListenAndServe(":"+port, nil)
 }
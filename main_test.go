@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+	"github.com/rschio/repoTagger/storage/sqlite"
+)
+
+func TestGitHubClient(t *testing.T) {
+	s := &server{token: "default"}
+	r1 := httptest.NewRequest("POST", "/repos/foo", nil)
+	r2 := httptest.NewRequest("POST", "/repos/bar", nil)
+	r3 := httptest.NewRequest("POST", "/repos/foo", nil)
+	r3.Header.Set("X-GitHub-Token", "other")
+
+	gh1, gh2 := s.github(r1), s.github(r2)
+	if gh1.Token != "default" || gh1.RateLimit != &s.rateLimit || gh2.RateLimit != &s.rateLimit {
+		t.Fatalf("expected clients of the default token sharing its rate limit")
+	}
+	if gh := s.github(r3); gh.Token != "other" || gh.RateLimit != nil {
+		t.Fatalf("expected a client of the forwarded token with its own rate limit; got %v", gh)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	w := httptest.NewRecorder()
+	if !rateLimited(w, repo.RateLimitErr(90*time.Second+time.Millisecond)) {
+		t.Fatalf("expected a rate limit error")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "91" {
+		t.Fatalf("expected status 429 retrying after 91s; got %d, %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if rateLimited(httptest.NewRecorder(), repo.NotFoundErr(0)) {
+		t.Fatalf("expected no rate limit error")
+	}
+}
+
+// newTestServer returns a server with a new database,
+// the returned function removes the database.
+func newTestServer(t *testing.T) (*server, func()) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	db, err := sqlite.New(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		t.Fatalf("database should be created")
+	}
+	learner, keywords := repo.NewLearner(), repo.NewKeywordIndex()
+	s := &server{
+		store:    learningStore{Storage: db, learner: learner, keywords: keywords},
+		learner:  learner,
+		keywords: keywords,
+		rules:    &rulesFile{rules: repo.DefaultRules()},
+	}
+	return s, func() { os.Remove(f.Name()) }
+}
+
+func TestImportFile(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	body := `[{"id": 1, "name": "a", "full_name": "x/a"}, {"id": 2, "name": "b", "full_name": "x/b"}]`
+	req := httptest.NewRequest("POST", "/import?user=foo", strings.NewReader(body))
+	// the default content type of curl --data-binary.
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.importFile(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201; got %d: %s", w.Code, w.Body)
+	}
+	result := &exportResult{}
+	if err := json.NewDecoder(w.Body).Decode(result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if result.Inserted != 2 || len(result.Repos) != 2 {
+		t.Fatalf("expected 2 inserted repos; got %+v", result.UpsertCount)
+	}
+	ids, err := s.store.Unstar("foo", nil, storage.Mark)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("expected 2 repos starred by the user; got %v, %v", ids, err)
+	}
+}
+
+func TestImportFileTooLarge(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+	defer func(size int64) { maxImportSize = size }(maxImportSize)
+	maxImportSize = 16
+
+	body := `[{"id": 1, "name": "a", "full_name": "x/a"}]`
+	req := httptest.NewRequest("POST", "/import", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.importFile(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+		t.Fatalf("expected status 400 with a too large body; got %d: %s", w.Code, w.Body)
+	}
+	if _, err := s.store.GetRepo(1); err == nil {
+		t.Fatalf("repo should not be stored")
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+	if err := s.store.InsertRepo(&repo.Repo{ID: 1, Name: "a", FullName: "x/a"}); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	if err := s.store.SetReadme(1, &repo.Readme{SHA: "a", Text: "A command line parser"}); err != nil {
+		t.Fatalf("failed to set readme: %v", err)
+	}
+
+	tt := []struct {
+		query string
+		code  int
+	}{
+		{"", http.StatusBadRequest},
+		{"parser", http.StatusOK},
+		{"router", http.StatusNotFound},
+		{`"command line`, http.StatusBadRequest},
+	}
+	for _, tc := range tt {
+		req := httptest.NewRequest("GET", "/search?q="+url.QueryEscape(tc.query), nil)
+		w := httptest.NewRecorder()
+		s.searchText(w, req)
+		if w.Code != tc.code {
+			t.Errorf("expected status %d to query %q; got %d", tc.code, tc.query, w.Code)
+		}
+	}
+}
+
+// fakeFetcher fetches the READMEs and releases
+// of its maps, the missing ones are not found.
+type fakeFetcher struct {
+	repo.Source
+	readmes  map[int]*repo.Readme
+	releases map[int]time.Time
+}
+
+func (f fakeFetcher) Readme(ctx context.Context, r *repo.Repo, old *repo.Readme) (*repo.Readme, error) {
+	readme, ok := f.readmes[r.ID]
+	if !ok {
+		return nil, repo.NotFoundErr(0)
+	}
+	return readme, nil
+}
+
+func (f fakeFetcher) LatestRelease(ctx context.Context, r *repo.Repo) (time.Time, error) {
+	at, ok := f.releases[r.ID]
+	if !ok {
+		return time.Time{}, repo.NotFoundErr(0)
+	}
+	return at, nil
+}
+
+func TestFetchEach(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	repos := make([]*repo.Repo, 3)
+	for i := range repos {
+		repos[i] = &repo.Repo{ID: i + 1, Name: "a", FullName: "x/a"}
+		if err := s.store.InsertRepo(repos[i]); err != nil {
+			t.Fatalf("failed to insert repo: %v", err)
+		}
+	}
+	src := fakeFetcher{
+		readmes: map[int]*repo.Readme{
+			1: {SHA: "a", Text: "A command line parser", ETag: `"a"`},
+			2: {SHA: "b", Text: "A web router", ETag: `"b"`},
+		},
+		releases: map[int]time.Time{1: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	// the README of repo 1 did not change.
+	if err := s.store.SetReadme(1, src.readmes[1]); err != nil {
+		t.Fatalf("failed to set readme: %v", err)
+	}
+
+	ctx := context.Background()
+	if n := s.fetchReadmes(ctx, src, repos); n != 1 {
+		t.Fatalf("expected 1 readme stored; got %d", n)
+	}
+	readme, err := s.store.GetReadme(2)
+	if err != nil || readme == nil || readme.SHA != "b" {
+		t.Fatalf("got wrong readme: %v, %v", readme, err)
+	}
+
+	for _, want := range []int{1, 0} {
+		if n := s.fetchReleases(ctx, src, repos); n != want {
+			t.Fatalf("expected %d releases stored; got %d", want, n)
+		}
+	}
+	r, err := s.store.GetRepo(1)
+	if err != nil || !r.ReleasedAt.Equal(src.releases[1]) {
+		t.Fatalf("expected release at %v; got %v, %v", src.releases[1], r, err)
+	}
+}
+
+func TestRulesFileReload(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testRules")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"rules": [{"tag": "go", "when": {"language": ["go"]}}]}`)
+	f.Close()
+
+	rf, err := loadRules(f.Name())
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	rules := rf.Rules()
+
+	modTime := time.Now().Add(time.Minute)
+	if err := ioutil.WriteFile(f.Name(), []byte(`{"rules": {}}`), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	os.Chtimes(f.Name(), modTime, modTime)
+	if err := rf.reload(); err == nil {
+		t.Fatalf("expected error reloading invalid rules")
+	}
+	// the same invalid file fails once.
+	if err := rf.reload(); err != nil {
+		t.Fatalf("expected invalid rules not reloaded again; got %v", err)
+	}
+	if rf.Rules() != rules {
+		t.Fatalf("expected the old rules kept")
+	}
+}
+
+func TestUnstarForget(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	// repo 1 is only starred by foo.
+	for id := 1; id <= 3; id++ {
+		r := &repo.Repo{ID: id, Name: "a", FullName: "x/a", Desc: "command line parser"}
+		if _, err := s.store.UpsertRepo(r); err != nil {
+			t.Fatalf("failed to upsert repo: %v", err)
+		}
+		r.Tags = []string{"cli"}
+		if err := s.store.UpdateTags(r); err != nil {
+			t.Fatalf("failed to update tags: %v", err)
+		}
+	}
+	if err := s.store.SetStarred("foo", []int{1, 2, 3}); err != nil {
+		t.Fatalf("failed to set starred: %v", err)
+	}
+	if err := s.store.SetStarred("bar", []int{2, 3}); err != nil {
+		t.Fatalf("failed to set starred: %v", err)
+	}
+
+	ids, err := s.store.Unstar("foo", nil, storage.Delete)
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("expected 3 unstarred repos; got %v, %v", ids, err)
+	}
+	suggestions := s.learner.Suggest(&repo.Repo{Desc: "command line parser"}, 0)
+	want := "learned from 2 repositories tagged cli"
+	if len(suggestions) != 1 || suggestions[0].Reason != want {
+		t.Fatalf("expected a suggestion %s; got %+v", want, suggestions)
+	}
+	suggestions = s.keywords.Suggest(&repo.Repo{Desc: "parser"})
+	want = `description has "parser", like 3 of 3 repositories`
+	if len(suggestions) != 1 || suggestions[0].Reason != want {
+		t.Fatalf("expected a suggestion %s; got %+v", want, suggestions)
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	// more repositories than workers and than jobUpdateEvery.
+	repos := make([]*repo.Repo, 12)
+	for i := range repos {
+		r := &repo.Repo{ID: i + 1, Name: "a", FullName: "x/a", Stars: 5}
+		if i%2 == 0 {
+			r.Stars = 20000
+		}
+		if err := s.store.InsertRepo(r); err != nil {
+			t.Fatalf("failed to insert repo: %v", err)
+		}
+		repos[i] = r
+	}
+
+	tt := []struct {
+		name    string
+		dryRun  bool
+		applied int
+	}{
+		{"dry run", true, 0},
+		{"apply", false, len(repos)},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			j := &storage.Job{Status: storage.JobRunning, DryRun: tc.dryRun,
+				Threshold: defaultThreshold, Total: len(repos)}
+			if err := s.store.CreateJob(j); err != nil {
+				t.Fatalf("failed to create job: %v", err)
+			}
+			s.runJob(j, repos)
+
+			got, err := s.store.GetJob(j.ID)
+			if err != nil {
+				t.Fatalf("failed to get job: %v", err)
+			}
+			if got.Status != storage.JobDone || got.Done != len(repos) ||
+				len(got.Report) != len(repos) || got.Applied != tc.applied {
+				t.Fatalf("got wrong job: %+v", got)
+			}
+			for _, result := range got.Report {
+				if len(result.Suggestions) == 0 {
+					t.Errorf("repo %d has no suggestions", result.RepoID)
+				}
+			}
+		})
+	}
+
+	for _, r := range repos {
+		stored, err := s.store.GetRepo(r.ID)
+		if err != nil {
+			t.Fatalf("failed to get repo: %v", err)
+		}
+		want := []string{"not-popular"}
+		if r.Stars > 10000 {
+			want = []string{"very-popular"}
+		}
+		if !reflect.DeepEqual(stored.Tags, want) {
+			t.Errorf("expected repo %d tags %v; got %v", r.ID, want, stored.Tags)
+		}
+	}
+}
+
+func TestApplyResult(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	repos := make([]*repo.Repo, 2)
+	for i := range repos {
+		repos[i] = &repo.Repo{ID: i + 1, Name: "a", FullName: "x/a",
+			Desc: "command line parser", Tags: []string{"go"}}
+		if err := s.store.InsertRepo(repos[i]); err != nil {
+			t.Fatalf("failed to insert repo: %v", err)
+		}
+	}
+	// the user tags repo 1 while the job runs.
+	tagged := &repo.Repo{ID: 1, Desc: "command line parser", Tags: []string{"go", "mine"}}
+	if err := s.store.UpdateTags(tagged); err != nil {
+		t.Fatalf("failed to update tags: %v", err)
+	}
+
+	for _, r := range repos {
+		result := &storage.JobResult{RepoID: r.ID, Suggestions: []repo.Suggestion{
+			{Tag: "cli", Score: 0.95, Rule: "readme"},
+			{Tag: "docker", Score: 0.9, Rule: "rules/docker"},
+			{Tag: "parser", Score: 0.6, Rule: "keywords"},
+		}}
+		s.applyResult(r, result, 0.9)
+		if want := []string{"cli", "docker"}; !reflect.DeepEqual(result.Applied, want) {
+			t.Fatalf("expected applied %v; got %v", want, result.Applied)
+		}
+	}
+
+	stored, err := s.store.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	sort.Strings(stored.Tags)
+	if want := []string{"cli", "docker", "go", "mine"}; !reflect.DeepEqual(stored.Tags, want) {
+		t.Fatalf("expected tags %v; got %v", want, stored.Tags)
+	}
+	// the tags applied by the job are not learned.
+	for _, sug := range s.learner.Suggest(&repo.Repo{Desc: "command line parser"}, 0) {
+		if sug.Tag == "cli" || sug.Tag == "docker" {
+			t.Fatalf("expected tag %s not learned", sug.Tag)
+		}
+	}
+}
+
+func TestStartJobThreshold(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	for _, v := range []string{"x", "-1", "1.5"} {
+		req := httptest.NewRequest("POST", "/jobs?threshold="+v, nil)
+		w := httptest.NewRecorder()
+		s.jobs(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 to threshold %s; got %d", v, w.Code)
+		}
+	}
+}
+
+func TestRunJobActivity(t *testing.T) {
+	s, done := newTestServer(t)
+	defer done()
+
+	// repo 2 is inserted after the activity is evaluated.
+	repos := make([]*repo.Repo, 2)
+	for i := range repos {
+		repos[i] = &repo.Repo{ID: i + 1, Name: "a", FullName: "x/a", Stars: 5,
+			PushedAt: time.Now().Add(-24 * time.Hour)}
+	}
+	if err := s.store.InsertRepo(repos[0]); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	if _, err := s.evaluateActivity(); err != nil {
+		t.Fatalf("failed to evaluate activity: %v", err)
+	}
+	if err := s.store.InsertRepo(repos[1]); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	repos, err := s.store.GetReposByTag("")
+	if err != nil || len(repos) != 2 {
+		t.Fatalf("expected 2 repos; got %d, %v", len(repos), err)
+	}
+
+	j := &storage.Job{Status: storage.JobRunning, Threshold: defaultThreshold, Total: len(repos)}
+	if err := s.store.CreateJob(j); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	s.runJob(j, repos)
+
+	for _, result := range j.Report {
+		suggested := false
+		for _, sug := range result.Suggestions {
+			suggested = suggested || sug.Tag == repo.TagActive
+		}
+		// the system tag of repo 1 is not suggested again.
+		if suggested != (result.RepoID == 2) {
+			t.Errorf("expected %s suggested to repo 2 only; got %+v", repo.TagActive, result)
+		}
+		r, err := s.store.GetRepo(result.RepoID)
+		if err != nil {
+			t.Fatalf("failed to get repo: %v", err)
+		}
+		if want := []string{"not-popular"}; !reflect.DeepEqual(r.Tags, want) {
+			t.Errorf("expected repo %d tags %v; got %v", r.ID, want, r.Tags)
+		}
+	}
+}
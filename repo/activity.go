@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Activity tags, they are given by SuggestActivity.
+const (
+	TagActive    = "active"
+	TagStale     = "stale"
+	TagArchived  = "archived"
+	TagAbandoned = "abandoned"
+)
+
+// ActivityRule is the Rule of the activity suggestions, their
+// tags are system tags and never added to the user tags.
+const ActivityRule = "activity"
+
+// Activity are the thresholds of the activity tags. The last
+// activity of a repository is its last push or release.
+type Activity struct {
+	// ActiveDays is the most days since the last activity
+	// of the active repositories. The default is 180.
+	ActiveDays int `json:"active_days,omitempty"`
+	// AbandonedDays is the days since the last activity
+	// after which a repository is abandoned. The default
+	// is 730.
+	AbandonedDays int `json:"abandoned_days,omitempty"`
+	// AbandonedIssueRatio is the open issues per star after
+	// which a stale repository is abandoned. The default
+	// is 0.2.
+	AbandonedIssueRatio float64 `json:"abandoned_issue_ratio,omitempty"`
+}
+
+// DefaultActivity returns the default thresholds.
+func DefaultActivity() *Activity {
+	return &Activity{ActiveDays: 180, AbandonedDays: 730, AbandonedIssueRatio: 0.2}
+}
+
+// setDefaults sets the default of the zero thresholds
+// and checks them.
+func (a *Activity) setDefaults() error {
+	d := DefaultActivity()
+	if a.ActiveDays == 0 {
+		a.ActiveDays = d.ActiveDays
+	}
+	if a.AbandonedDays == 0 {
+		a.AbandonedDays = d.AbandonedDays
+	}
+	if a.AbandonedIssueRatio == 0 {
+		a.AbandonedIssueRatio = d.AbandonedIssueRatio
+	}
+	if a.ActiveDays < 0 || a.AbandonedDays <= a.ActiveDays {
+		return fmt.Errorf("activity: active_days %d must be positive and less than abandoned_days %d",
+			a.ActiveDays, a.AbandonedDays)
+	}
+	if a.AbandonedIssueRatio < 0 {
+		return fmt.Errorf("activity: negative abandoned_issue_ratio %v", a.AbandonedIssueRatio)
+	}
+	return nil
+}
+
+// Suggest suggests the activity tag of r, archived, active,
+// stale or abandoned. A repository without push or release
+// time has no activity tag.
+func (a *Activity) Suggest(r *Repo) []Suggestion {
+	return a.suggestAt(r, time.Now())
+}
+
+func (a *Activity) suggestAt(r *Repo, now time.Time) []Suggestion {
+	suggest := func(tag, reason string) []Suggestion {
+		return []Suggestion{{Tag: tag, Score: 1, Rule: ActivityRule, Reason: reason}}
+	}
+	if r.Archived {
+		return suggest(TagArchived, "archived in the source")
+	}
+
+	last, what := r.PushedAt, "pushed"
+	if r.ReleasedAt.After(last) {
+		last, what = r.ReleasedAt, "released"
+	}
+	if last.IsZero() {
+		return []Suggestion{}
+	}
+	days := int(now.Sub(last).Hours() / 24)
+	reason := fmt.Sprintf("last %s %d days ago", what, days)
+
+	stars := r.Stars
+	if stars < 1 {
+		stars = 1
+	}
+	ratio := float64(r.OpenIssues) / float64(stars)
+	switch {
+	case days <= a.ActiveDays:
+		return suggest(TagActive, reason)
+	case days >= a.AbandonedDays:
+		return suggest(TagAbandoned, reason)
+	case ratio >= a.AbandonedIssueRatio:
+		return suggest(TagAbandoned, fmt.Sprintf("%s with %d open issues and %d stars",
+			reason, r.OpenIssues, r.Stars))
+	}
+	return suggest(TagStale, reason)
+}
+
+// ReleaseFetcher is a Source that can fetch the
+// time of the latest release.
+type ReleaseFetcher interface {
+	Source
+	LatestRelease(ctx context.Context, r *Repo) (time.Time, error)
+}
+
+var _ ReleaseFetcher = (*GitHub)(nil)
+
+// LatestRelease fetches when the latest release of r was
+// published, a repository without releases returns NotFoundErr.
+func (g *GitHub) LatestRelease(ctx context.Context, r *Repo) (time.Time, error) {
+	res, err := g.requestPage(ctx, g.baseURL()+"/repos/"+fullName(r)+"/releases/latest", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		var notFound NotFoundErr
+		return time.Time{}, notFound
+	}
+	if res.StatusCode != 200 {
+		return time.Time{}, statusErr(res)
+	}
+
+	var release struct {
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&release); err != nil {
+		return time.Time{}, err
+	}
+	return release.PublishedAt, nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestActivity(t *testing.T) {
+	a := &Activity{ActiveDays: 90}
+	if err := a.setDefaults(); err != nil {
+		t.Fatalf("failed to set defaults: %v", err)
+	}
+	if a.AbandonedDays != 730 || a.AbandonedIssueRatio != 0.2 {
+		t.Fatalf("expected the default thresholds; got %+v", a)
+	}
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	tt := []struct {
+		name   string
+		r      *Repo
+		tag    string
+		reason string
+	}{
+		{"archived", &Repo{Archived: true, PushedAt: days(1)}, TagArchived,
+			"archived in the source"},
+		{"active", &Repo{PushedAt: days(30)}, TagActive, "last pushed 30 days ago"},
+		{"released", &Repo{PushedAt: days(400), ReleasedAt: days(60)}, TagActive,
+			"last released 60 days ago"},
+		{"stale", &Repo{PushedAt: days(400), Stars: 100, OpenIssues: 10}, TagStale,
+			"last pushed 400 days ago"},
+		{"issues", &Repo{PushedAt: days(400), Stars: 100, OpenIssues: 20}, TagAbandoned,
+			"last pushed 400 days ago with 20 open issues and 100 stars"},
+		{"abandoned", &Repo{PushedAt: days(800), Stars: 100}, TagAbandoned,
+			"last pushed 800 days ago"},
+		{"unknown", &Repo{}, "", ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := a.suggestAt(tc.r, now)
+			if tc.tag == "" {
+				if len(got) != 0 {
+					t.Fatalf("expected no suggestion; got %+v", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0].Tag != tc.tag || got[0].Reason != tc.reason ||
+				got[0].Rule != "activity" {
+				t.Fatalf("expected %s because %q; got %+v", tc.tag, tc.reason, got)
+			}
+		})
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/foo/bar/releases/latest":
+			fmt.Fprint(w, `{"tag_name": "v1.0.0", "published_at": "2021-01-02T03:04:05Z"}`)
+		default:
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	g := &GitHub{BaseURL: s.URL}
+	at, err := g.LatestRelease(context.Background(), &Repo{FullName: "foo/bar"})
+	if err != nil {
+		t.Fatalf("failed to get release: %v", err)
+	}
+	if !at.Equal(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("got wrong release time %v", at)
+	}
+	if _, err := g.LatestRelease(context.Background(), &Repo{FullName: "foo/baz"}); err != NotFoundErr(0) {
+		t.Fatalf("expected NotFoundErr; got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Collections of repositories of a user.
+const (
+	// CollectionStarred are the repositories starred by the user.
+	CollectionStarred = "starred"
+	// CollectionOwned are the repositories owned by the user.
+	CollectionOwned = "owned"
+	// CollectionWatched are the repositories watched by the user.
+	CollectionWatched = "watched"
+	// CollectionOrg are the repositories of an organization.
+	CollectionOrg = "org"
+)
+
+// Collection returns all the repositories of collection of
+// user, for CollectionOrg user is the organization. Like
+// GetRepos it returns a PartialErr if some pages fail.
+func (g *GitHub) Collection(ctx context.Context, collection, user string) ([]*Repo, error) {
+	var urlFormat string
+	switch collection {
+	case CollectionStarred:
+		return g.GetReposContext(ctx, user)
+	case CollectionOwned:
+		urlFormat = "/users/" + user + "/repos?type=owner&per_page=100&page=%d"
+	case CollectionWatched:
+		urlFormat = "/users/" + user + "/subscriptions?per_page=100&page=%d"
+	case CollectionOrg:
+		urlFormat = "/orgs/" + user + "/repos?type=all&per_page=100&page=%d"
+	default:
+		return nil, fmt.Errorf("unknown collection %q", collection)
+	}
+	return g.getRepos(ctx, g.baseURL()+urlFormat)
+}
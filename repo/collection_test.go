@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	paths := map[string]string{
+		"/users/foo/repos":         "type=owner&per_page=100&page=1",
+		"/users/foo/subscriptions": "per_page=100&page=1",
+		"/orgs/foo/repos":          "type=all&per_page=100&page=1",
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if query, ok := paths[r.URL.Path]; !ok || r.URL.RawQuery != query {
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `[{"id": 1, "name": "repo", "full_name": "foo/repo",
+			"owner": {"login": "foo", "type": "Organization"}}]`)
+	}))
+	defer s.Close()
+
+	g := &GitHub{BaseURL: s.URL}
+	for _, c := range []string{CollectionOwned, CollectionWatched, CollectionOrg} {
+		rs, err := g.Collection(context.Background(), c, "foo")
+		if err != nil {
+			t.Fatalf("failed to get %s repos: %v", c, err)
+		}
+		if len(rs) != 1 || rs[0].FullName != "foo/repo" || rs[0].OwnerType != "Organization" {
+			t.Fatalf("got wrong %s repos: %+v", c, rs)
+		}
+	}
+
+	if _, err := g.Collection(context.Background(), "forked", "foo"); err == nil {
+		t.Fatalf("expected error of unknown collection")
+	}
+	if _, err := g.Collection(context.Background(), CollectionOrg, "bar"); err != NotFoundErr(0) {
+		t.Fatalf("expected not found; got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReadExport reads the repositories of a starred list exported
+// from the GitHub API, like the output of
+// `gh api --paginate user/starred`. The list can be a JSON array,
+// many concatenated arrays, one per page, or one repository per
+// line. Both the plain and the star+json formats are accepted.
+func ReadExport(r io.Reader) ([]*Repo, error) {
+	dec := json.NewDecoder(r)
+	repos := make([]*Repo, 0)
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return repos, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value %d: %v", n, err)
+		}
+
+		data := bytes.TrimSpace(raw)
+		// one repository per line.
+		if len(data) > 0 && data[0] == '{' {
+			data = append(append([]byte{'['}, data...), ']')
+		}
+		rs, err := unmarshalRepos(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value %d: %v", n, err)
+		}
+		repos = append(repos, rs...)
+	}
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadExport(t *testing.T) {
+	tt := []struct {
+		name  string
+		data  string
+		names []string
+	}{
+		{"array", `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`, []string{"a", "b"}},
+		{"paginated", "[{\"id\":1,\"name\":\"a\"}]\n[{\"id\":2,\"name\":\"b\"}]\n[]",
+			[]string{"a", "b"}},
+		{"ndjson", "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n", []string{"a", "b"}},
+		{"star", `[{"starred_at":"2020-01-02T03:04:05Z","repo":{"id":1,"name":"a"}}]`,
+			[]string{"a"}},
+		{"empty", "", []string{}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rs, err := ReadExport(strings.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("failed to read export: %v", err)
+			}
+			if len(rs) != len(tc.names) {
+				t.Fatalf("expected %d repos; got %d", len(tc.names), len(rs))
+			}
+			for i, r := range rs {
+				if r.Name != tc.names[i] || r.Source != SourceGitHub {
+					t.Errorf("expected github repo %s; got %+v", tc.names[i], r)
+				}
+			}
+		})
+	}
+
+	if _, err := ReadExport(strings.NewReader(`[{"id":1}`)); err == nil {
+		t.Errorf("expected error on truncated export")
+	}
+}
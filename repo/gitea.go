@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SourceGitea is the name of the Gitea source.
+const SourceGitea = "gitea"
+
+// Gitea requests starred repositories from a Gitea
+// or Forgejo instance.
+type Gitea struct {
+	// BaseURL is the URL of the instance, like
+	// https://codeberg.org.
+	BaseURL string
+	// Token is an access token sent with every request.
+	// An empty Token makes anonymous requests.
+	Token string
+}
+
+var _ Source = (*Gitea)(nil)
+
+// String describes g without revealing the token.
+func (g *Gitea) String() string {
+	if g.Token == "" {
+		return "Gitea{" + g.BaseURL + ", anonymous}"
+	}
+	return "Gitea{" + g.BaseURL + ", token: [redacted]}"
+}
+
+// giteaRepo is a repository of the Gitea API.
+type giteaRepo struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	Description string    `json:"description"`
+	HTMLURL     string    `json:"html_url"`
+	Language    string    `json:"language"`
+	Stars       int       `json:"stars_count"`
+	Forks       int       `json:"forks_count"`
+	OpenIssues  int       `json:"open_issues_count"`
+	Archived    bool      `json:"archived"`
+	Fork        bool      `json:"fork"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Topics      []string  `json:"topics"`
+	Owner       struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func (gr *giteaRepo) repo() *Repo {
+	return &Repo{
+		Name:       gr.Name,
+		Desc:       gr.Description,
+		URLHTTP:    gr.HTMLURL,
+		Lang:       gr.Language,
+		Source:     SourceGitea,
+		SourceID:   gr.ID,
+		Owner:      gr.Owner.Login,
+		FullName:   gr.FullName,
+		Topics:     gr.Topics,
+		Stars:      gr.Stars,
+		Forks:      gr.Forks,
+		OpenIssues: gr.OpenIssues,
+		Archived:   gr.Archived,
+		Fork:       gr.Fork,
+		CreatedAt:  gr.CreatedAt,
+		// Gitea has no push time, updated_at changes on push.
+		PushedAt:  gr.UpdatedAt,
+		UpdatedAt: gr.UpdatedAt,
+	}
+}
+
+// Name returns SourceGitea.
+func (g *Gitea) Name() string { return SourceGitea }
+
+// Starred returns all the repositories starred by user. If a
+// page fails the repositories of the previous pages are returned
+// with a PartialErr.
+func (g *Gitea) Starred(ctx context.Context, user string) ([]*Repo, error) {
+	next := g.BaseURL + "/api/v1/users/" + url.PathEscape(user) + "/starred?limit=50"
+	return followPages(ctx, next, 1, g.getPage)
+}
+
+// getPage returns the repositories of the page and its header.
+func (g *Gitea) getPage(ctx context.Context, pageURL string) ([]*Repo, http.Header, error) {
+	res, err := g.get(ctx, pageURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		var notFound NotFoundErr
+		return nil, nil, notFound
+	}
+	if res.StatusCode != 200 {
+		return nil, nil, statusErr(res)
+	}
+
+	grs := make([]*giteaRepo, 0)
+	if err := json.NewDecoder(res.Body).Decode(&grs); err != nil {
+		return nil, nil, err
+	}
+	repos := make([]*Repo, len(grs))
+	for i, gr := range grs {
+		repos[i] = gr.repo()
+	}
+	return repos, res.Header, nil
+}
+
+// Details fetches the repository r from Gitea.
+func (g *Gitea) Details(ctx context.Context, r *Repo) (*Repo, error) {
+	gr, err := g.repository(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	details := gr.repo()
+	// keep the catalog id and the user's data.
+	details.ID, details.Tags = r.ID, r.Tags
+	details.StarredAt, details.Status = r.StarredAt, r.Status
+	return details, nil
+}
+
+func (g *Gitea) repository(ctx context.Context, r *Repo) (*giteaRepo, error) {
+	res, err := g.get(ctx, fmt.Sprintf("%s/api/v1/repositories/%d", g.BaseURL, r.SourceID))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		var notFound NotFoundErr
+		return nil, notFound
+	}
+	if res.StatusCode != 200 {
+		return nil, statusErr(res)
+	}
+
+	gr := &giteaRepo{}
+	if err := json.NewDecoder(res.Body).Decode(gr); err != nil {
+		return nil, err
+	}
+	return gr, nil
+}
+
+// get requests url with the token of g, if any.
+func (g *Gitea) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "token "+g.Token)
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// giteaServer serves 2 pages of starred repositories of
+// the user foo following the Link header.
+type giteaServer struct {
+	url string
+}
+
+func (gs *giteaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token secret" {
+		http.Error(w, http.StatusText(401), http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/api/v1/users/foo/starred" {
+		http.Error(w, http.StatusText(404), http.StatusNotFound)
+		return
+	}
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		fmt.Sscan(p, &page)
+	}
+	if page == 1 {
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?limit=50&page=2>; rel="next",`+
+			`<%s%s?limit=50&page=2>; rel="last"`, gs.url, r.URL.Path, gs.url, r.URL.Path))
+	}
+	fmt.Fprintf(w, `[{"id": %d, "name": "repo%d", "html_url": "https://git.example.com/foo/repo%d",
+		"language": "Go", "stars_count": 10}, {"id": %d, "name": "repo%d"}]`,
+		page*2-1, page*2-1, page*2-1, page*2, page*2)
+}
+
+func TestGiteaStarred(t *testing.T) {
+	gs := &giteaServer{}
+	s := httptest.NewServer(gs)
+	defer s.Close()
+	gs.url = s.URL
+
+	g := &Gitea{BaseURL: s.URL, Token: "secret"}
+	rs, err := g.Starred(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("failed to get starred repos: %v", err)
+	}
+	if len(rs) != 4 {
+		t.Fatalf("expected 4 repos; got %d", len(rs))
+	}
+	for i, r := range rs {
+		if r.Source != SourceGitea || r.SourceID != i+1 || r.Name != fmt.Sprintf("repo%d", i+1) {
+			t.Fatalf("got wrong repo %+v", r)
+		}
+	}
+	if rs[0].Lang != "Go" || rs[0].URLHTTP != "https://git.example.com/foo/repo1" {
+		t.Fatalf("got wrong repo %+v", rs[0])
+	}
+
+	if _, err := g.Starred(context.Background(), "bar"); err != NotFoundErr(0) {
+		t.Fatalf("expected not found; got %v", err)
+	}
+}
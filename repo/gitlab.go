@@ -0,0 +1,227 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SourceGitLab is the name of the GitLab source.
+const SourceGitLab = "gitlab"
+
+// GitLab requests starred projects from a GitLab instance.
+type GitLab struct {
+	// BaseURL is the URL of the instance, like
+	// https://gitlab.com.
+	BaseURL string
+	// Token is a personal access token sent with every
+	// request. An empty Token makes anonymous requests.
+	Token string
+}
+
+var _ Source = (*GitLab)(nil)
+
+// String describes g without revealing the token.
+func (g *GitLab) String() string {
+	if g.Token == "" {
+		return "GitLab{" + g.BaseURL + ", anonymous}"
+	}
+	return "GitLab{" + g.BaseURL + ", token: [redacted]}"
+}
+
+// gitlabProject is a project of the GitLab API.
+type gitlabProject struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	WebURL            string `json:"web_url"`
+	Stars             int    `json:"star_count"`
+	Forks             int    `json:"forks_count"`
+	OpenIssues        int    `json:"open_issues_count"`
+	Archived          bool   `json:"archived"`
+	// ForkedFrom is not nil in forks.
+	ForkedFrom     *struct{} `json:"forked_from_project"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	// Topics replaced TagList in GitLab 14.
+	Topics    []string `json:"topics"`
+	TagList   []string `json:"tag_list"`
+	Namespace struct {
+		Kind     string `json:"kind"`
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	License struct {
+		Key string `json:"key"`
+	} `json:"license"`
+}
+
+func (p *gitlabProject) repo() *Repo {
+	r := &Repo{
+		Name:       p.Name,
+		Desc:       p.Description,
+		URLHTTP:    p.WebURL,
+		Source:     SourceGitLab,
+		SourceID:   p.ID,
+		Owner:      p.Namespace.FullPath,
+		FullName:   p.PathWithNamespace,
+		Stars:      p.Stars,
+		Forks:      p.Forks,
+		OpenIssues: p.OpenIssues,
+		Archived:   p.Archived,
+		Fork:       p.ForkedFrom != nil,
+		License:    p.License.Key,
+		CreatedAt:  p.CreatedAt,
+		PushedAt:   p.LastActivityAt,
+		UpdatedAt:  p.LastActivityAt,
+	}
+	switch p.Namespace.Kind {
+	case "user":
+		r.OwnerType = "User"
+	case "group":
+		r.OwnerType = "Organization"
+	}
+	r.Topics = p.Topics
+	if len(r.Topics) == 0 {
+		r.Topics = p.TagList
+	}
+	return r
+}
+
+// Name returns SourceGitLab.
+func (g *GitLab) Name() string { return SourceGitLab }
+
+// Starred returns all the projects starred by user, the id or
+// username of a GitLab user. Pages are followed by the Link header
+// of keyset pagination or by the X-Next-Page header of offset
+// pagination. If a page fails the projects of the previous
+// pages are returned with a PartialErr.
+func (g *GitLab) Starred(ctx context.Context, user string) ([]*Repo, error) {
+	next := g.BaseURL + "/api/v4/users/" + url.PathEscape(user) +
+		"/starred_projects?per_page=100&order_by=id&sort=asc"
+	return g.starred(ctx, next)
+}
+
+func (g *GitLab) starred(ctx context.Context, next string) ([]*Repo, error) {
+	return followPages(ctx, next, 1, g.getPage)
+}
+
+// followPages gets the repositories of the page next, numbered
+// first, and of the pages after it. If a page after the page 1
+// fails the repositories of the previous pages are returned with
+// a PartialErr.
+func followPages(ctx context.Context, next string, first int,
+	getPage func(context.Context, string) ([]*Repo, http.Header, error)) ([]*Repo, error) {
+	allRepos := make([]*Repo, 0)
+	for page := first; next != ""; page++ {
+		repos, header, err := getPage(ctx, next)
+		if err != nil {
+			if page == 1 || ctx.Err() != nil {
+				return nil, err
+			}
+			return allRepos, PartialErr{{Page: page, Err: err}}
+		}
+		allRepos = append(allRepos, repos...)
+		next = nextPage(header, next)
+	}
+	return allRepos, nil
+}
+
+// getPage returns the projects of the page and its header.
+func (g *GitLab) getPage(ctx context.Context, pageURL string) ([]*Repo, http.Header, error) {
+	res, err := g.get(ctx, pageURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		var notFound NotFoundErr
+		return nil, nil, notFound
+	}
+	if res.StatusCode != 200 {
+		return nil, nil, statusErr(res)
+	}
+
+	projects := make([]*gitlabProject, 0)
+	if err := json.NewDecoder(res.Body).Decode(&projects); err != nil {
+		return nil, nil, err
+	}
+	repos := make([]*Repo, len(projects))
+	for i, p := range projects {
+		repos[i] = p.repo()
+	}
+	return repos, res.Header, nil
+}
+
+// nextPage returns the url of the page after pageURL, from the
+// Link or X-Next-Page headers, or an empty string if it is the
+// last page.
+func nextPage(header http.Header, pageURL string) string {
+	if next, ok := parseLinks(header)["next"]; ok {
+		return next
+	}
+	page := header.Get("X-Next-Page")
+	if page == "" {
+		return ""
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("page", page)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Details fetches the project r from GitLab.
+func (g *GitLab) Details(ctx context.Context, r *Repo) (*Repo, error) {
+	p, err := g.project(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	details := p.repo()
+	// keep the catalog id and the user's data.
+	details.ID, details.Tags = r.ID, r.Tags
+	details.StarredAt, details.Status = r.StarredAt, r.Status
+	return details, nil
+}
+
+func (g *GitLab) project(ctx context.Context, r *Repo) (*gitlabProject, error) {
+	u := fmt.Sprintf("%s/api/v4/projects/%d?license=true", g.BaseURL, r.SourceID)
+	res, err := g.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		var notFound NotFoundErr
+		return nil, notFound
+	}
+	if res.StatusCode != 200 {
+		return nil, statusErr(res)
+	}
+
+	p := &gitlabProject{}
+	if err := json.NewDecoder(res.Body).Decode(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// get requests url with the token of g, if any.
+func (g *GitLab) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.Token)
+	}
+	return client.Do(req)
+}
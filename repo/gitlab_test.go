@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gitlabServer serves 3 pages of starred projects of the
+// user foo with offset or keyset pagination.
+type gitlabServer struct {
+	t      *testing.T
+	keyset bool
+	url    string
+}
+
+func (gs *gitlabServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+		http.Error(w, http.StatusText(401), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/v4/users/foo/starred_projects":
+	case "/api/v4/projects/3":
+		w.Write([]byte(`{"id": 3, "star_count": 2000, "license": {"key": "mit"},
+			"namespace": {"kind": "group"}}`))
+		return
+	default:
+		http.Error(w, http.StatusText(404), http.StatusNotFound)
+		return
+	}
+
+	page := 1
+	if gs.keyset {
+		var after int
+		fmt.Sscan(r.URL.Query().Get("id_after"), &after)
+		page = after + 1
+		if page < 3 {
+			next := fmt.Sprintf("%s%s?pagination=keyset&id_after=%d", gs.url, r.URL.Path, page)
+			w.Header().Set("Link", `<`+next+`>; rel="next"`)
+		}
+	} else {
+		if p := r.URL.Query().Get("page"); p != "" {
+			fmt.Sscan(p, &page)
+		}
+		if page < 3 {
+			w.Header().Set("X-Next-Page", fmt.Sprint(page+1))
+		}
+	}
+
+	fmt.Fprintf(w, `[{"id": %d, "name": "project%d", "description": "desc",
+		"web_url": "https://gitlab.com/foo/project%d", "topics": ["go", "cli"]}]`,
+		page, page, page)
+}
+
+func TestGitLabStarred(t *testing.T) {
+	for _, keyset := range []bool{false, true} {
+		t.Run(fmt.Sprintf("keyset %t", keyset), func(t *testing.T) {
+			gs := &gitlabServer{t: t, keyset: keyset}
+			s := httptest.NewServer(gs)
+			defer s.Close()
+			gs.url = s.URL
+
+			g := &GitLab{BaseURL: s.URL, Token: "secret"}
+			rs, err := g.Starred(context.Background(), "foo")
+			if err != nil {
+				t.Fatalf("failed to get starred projects: %v", err)
+			}
+			if len(rs) != 3 {
+				t.Fatalf("expected 3 projects; got %d", len(rs))
+			}
+			for i, r := range rs {
+				if r.Source != SourceGitLab || r.SourceID != i+1 ||
+					r.URLHTTP != fmt.Sprintf("https://gitlab.com/foo/project%d", i+1) {
+					t.Fatalf("got wrong project %+v", r)
+				}
+				if len(r.Topics) != 2 || r.Topics[0] != "go" || r.Topics[1] != "cli" {
+					t.Fatalf("expected topics go and cli; got %v", r.Topics)
+				}
+			}
+		})
+	}
+
+	s := httptest.NewServer(&gitlabServer{t: t})
+	defer s.Close()
+	g := &GitLab{BaseURL: s.URL, Token: "secret"}
+	if _, err := g.Starred(context.Background(), "bar"); err != NotFoundErr(0) {
+		t.Fatalf("expected not found; got %v", err)
+	}
+
+	details, err := g.Details(context.Background(), &Repo{ID: 7, SourceID: 3})
+	if err != nil {
+		t.Fatalf("failed to get details: %v", err)
+	}
+	if details.ID != 7 {
+		t.Fatalf("expected catalog id 7; got %d", details.ID)
+	}
+	suggestions := suggest(details)
+	expected := []string{"popular", "Organization-owner", "mit"}
+	if fmt.Sprint(suggestions) != fmt.Sprint(expected) {
+		t.Fatalf("expected suggestions %v; got %v", expected, suggestions)
+	}
+}
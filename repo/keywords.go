@@ -0,0 +1,235 @@
+package repo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// synonyms are the words replaced by their usual tag.
+var synonyms = map[string]string{
+	"k8s":          "kubernetes",
+	"kube":         "kubernetes",
+	"golang":       "go",
+	"js":           "javascript",
+	"nodejs":       "node",
+	"ts":           "typescript",
+	"py":           "python",
+	"rb":           "ruby",
+	"ml":           "machine-learning",
+	"dl":           "deep-learning",
+	"db":           "database",
+	"postgres":     "postgresql",
+	"mongo":        "mongodb",
+	"reactjs":      "react",
+	"vuejs":        "vue",
+	"osx":          "macos",
+	"webassembly":  "wasm",
+	"command-line": "cli",
+	"commandline":  "cli",
+}
+
+// synonymTags are the tags of synonyms, they are
+// kept as they are, like kubernetes.
+var synonymTags = func() map[string]bool {
+	tags := make(map[string]bool, len(synonyms))
+	for _, tag := range synonyms {
+		tags[tag] = true
+	}
+	return tags
+}()
+
+// keyword is a word of a description.
+type keyword struct {
+	// word is the word as it is, stem is its stem
+	// and tag is the singular of word or its synonym.
+	word, stem, tag string
+}
+
+// Keywords returns the keywords of text, its words in lower
+// case without stopwords, with the synonyms replaced and in
+// singular.
+func Keywords(text string) []string {
+	kws := extractKeywords(text)
+	words := make([]string, len(kws))
+	for i, kw := range kws {
+		words[i] = kw.tag
+	}
+	return words
+}
+
+func extractKeywords(text string) []keyword {
+	kws := make([]keyword, 0)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), notWord) {
+		w = strings.Trim(w, "-")
+		if tag, ok := synonyms[w]; ok || synonymTags[w] {
+			if !ok {
+				tag = w
+			}
+			kws = append(kws, keyword{word: w, stem: tag, tag: tag})
+			continue
+		}
+		if len(w) < 3 || strings.Trim(w, "0123456789") == "" {
+			continue
+		}
+		singular := singular(w)
+		if stopwords[singular] {
+			continue
+		}
+		kws = append(kws, keyword{word: w, stem: Stem(w), tag: singular})
+	}
+	return kws
+}
+
+// singular removes the plural suffix of w.
+func singular(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 4 && strings.HasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") &&
+		!strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// stemSuffixes are the suffixes removed by Stem, in order.
+var stemSuffixes = []string{"ing", "er", "ed", "ly"}
+
+// Stem returns the stem of the lower case word w, its singular
+// without the suffixes like -ing and -er, so parser and parsing
+// have the same stem. The stem has at least 3 letters.
+func Stem(w string) string {
+	w = singular(w)
+	for _, suffix := range stemSuffixes {
+		if strings.HasSuffix(w, suffix) && len(w)-len(suffix) >= 3 {
+			return w[:len(w)-len(suffix)]
+		}
+	}
+	return w
+}
+
+// KeywordIndex has the keywords of the descriptions of the
+// catalog, it weights the keywords of a description by TF-IDF
+// so the words of many repositories are not suggested. It is
+// safe for concurrent use.
+type KeywordIndex struct {
+	mu sync.Mutex
+	// docs are the stems of the indexed repos.
+	docs map[int][]string
+	// df is the number of repos of each stem.
+	df map[string]int
+}
+
+// keywordScore is the highest score of the keyword
+// suggestions, a description keyword can be incidental,
+// and maxKeywords the most keywords suggested.
+const (
+	keywordScore = 0.6
+	maxKeywords  = 5
+)
+
+// NewKeywordIndex returns an empty KeywordIndex.
+func NewKeywordIndex() *KeywordIndex {
+	return &KeywordIndex{
+		docs: make(map[int][]string),
+		df:   make(map[string]int),
+	}
+}
+
+// Add indexes the description of r, replacing what was
+// indexed from r before.
+func (k *KeywordIndex) Add(r *Repo) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.remove(r.ID)
+
+	stems := make([]string, 0)
+	for _, kw := range extractKeywords(r.Desc) {
+		stems = append(stems, kw.stem)
+	}
+	stems = uniq(stems)
+	k.docs[r.ID] = stems
+	for _, stem := range stems {
+		k.df[stem]++
+	}
+}
+
+// Remove removes the repository id from the index.
+func (k *KeywordIndex) Remove(id int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.remove(id)
+}
+
+func (k *KeywordIndex) remove(id int) {
+	stems, ok := k.docs[id]
+	if !ok {
+		return
+	}
+	delete(k.docs, id)
+	for _, stem := range stems {
+		if k.df[stem]--; k.df[stem] == 0 {
+			delete(k.df, stem)
+		}
+	}
+}
+
+// Suggest suggests the keywords of the description of r with
+// the highest TF-IDF, the most specific first. The Score of a
+// keyword of r only is keywordScore.
+func (k *KeywordIndex) Suggest(r *Repo) []Suggestion {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	tf := make(map[string]int)
+	// the first keyword of each stem.
+	first := make(map[string]keyword)
+	for _, kw := range extractKeywords(r.Desc) {
+		if _, ok := first[kw.stem]; !ok {
+			first[kw.stem] = kw
+		}
+		tf[kw.stem]++
+	}
+
+	// r is counted in the catalog even if it is not indexed.
+	n := len(k.docs)
+	_, indexed := k.docs[r.ID]
+	if !indexed {
+		n++
+	}
+	maxIDF := math.Log(float64(n) + 1)
+
+	suggestions := make([]Suggestion, 0)
+	for stem, count := range tf {
+		df := k.df[stem]
+		if !indexed {
+			df++
+		}
+		weight := float64(count) * math.Log(float64(n+1)/float64(df)) / maxIDF
+		if weight <= 0 {
+			continue
+		}
+		kw := first[stem]
+		suggestions = append(suggestions, Suggestion{
+			Tag:    kw.tag,
+			Score:  keywordScore * math.Min(weight, 1),
+			Rule:   "keywords",
+			Reason: fmt.Sprintf("description has %q, like %d of %d repositories", kw.word, df, n),
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	if len(suggestions) > maxKeywords {
+		suggestions = suggestions[:maxKeywords]
+	}
+	return suggestions
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	got := Keywords("A fast K8s operator, written in Golang: parsers for the Kubernetes clusters!")
+	want := []string{"kubernetes", "operator", "go", "parser", "kubernetes", "cluster"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v; got %v", want, got)
+	}
+
+	stems := []struct{ word, stem string }{
+		{"parser", "pars"},
+		{"parsing", "pars"},
+		{"parsers", "pars"},
+		{"libraries", "library"},
+		{"classes", "class"},
+		{"status", "status"},
+		{"used", "used"},
+	}
+	for _, s := range stems {
+		if got := Stem(s.word); got != s.stem {
+			t.Errorf("expected stem of %s %s; got %s", s.word, s.stem, got)
+		}
+	}
+}
+
+func TestKeywordIndex(t *testing.T) {
+	k := NewKeywordIndex()
+	descs := []string{
+		"A terminal emulator",
+		"A terminal file manager",
+		"Terminal UI for git",
+		"Parsing terminal escape sequences",
+	}
+	for i, desc := range descs {
+		k.Add(&Repo{ID: i + 1, Desc: desc})
+	}
+
+	r := &Repo{ID: 4, Desc: "Parsing terminal escape sequences"}
+	got := k.Suggest(r)
+	// terminal is in all the repositories.
+	want := []string{"escape", "parsing", "sequence", "terminal"}
+	if !reflect.DeepEqual(Tags(got), want) {
+		t.Fatalf("expected %v; got %v", want, Tags(got))
+	}
+	if got[0].Score != keywordScore || got[0].Rule != "keywords" ||
+		got[0].Reason != `description has "escape", like 1 of 4 repositories` ||
+		got[3].Score >= 0.1 {
+		t.Errorf("got wrong suggestion %+v", got[0])
+	}
+
+	// a repository out of the catalog counts too.
+	got = k.Suggest(&Repo{Desc: "A terminal parser"})
+	if len(got) != 2 || got[0].Tag != "parser" || got[1].Tag != "terminal" ||
+		got[0].Score <= got[1].Score {
+		t.Fatalf("got wrong suggestions %+v", got)
+	}
+
+	for i := range descs {
+		k.Remove(i + 1)
+	}
+	if len(k.docs) != 0 || len(k.df) != 0 {
+		t.Fatalf("expected empty index; got %v", k.df)
+	}
+}
@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Learner learns which tags the user gives to the repositories
+// with a naive Bayes classifier over the tokens of their
+// description, language and topics. It is safe for concurrent
+// use.
+type Learner struct {
+	mu sync.Mutex
+	// examples are the tokens and tags of the learned repos.
+	examples map[int]example
+	// tagDocs is the number of repos of each tag.
+	tagDocs map[string]int
+	// tokenDocs is the number of repos of each token.
+	tokenDocs map[string]int
+	// tagTokens is the number of repos of each tag and token.
+	tagTokens map[string]map[string]int
+}
+
+type example struct {
+	tokens []string
+	tags   []string
+}
+
+// minTagDocs is the number of repositories a tag
+// needs to be suggested by the Learner.
+const minTagDocs = 2
+
+// NewLearner returns a Learner that knows nothing.
+func NewLearner() *Learner {
+	return &Learner{
+		examples:  make(map[int]example),
+		tagDocs:   make(map[string]int),
+		tokenDocs: make(map[string]int),
+		tagTokens: make(map[string]map[string]int),
+	}
+}
+
+// Learn learns the tags of r, replacing what was learned
+// from r before. A repository without tags is forgotten.
+func (l *Learner) Learn(r *Repo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.forget(r.ID)
+	if len(r.Tags) == 0 {
+		return
+	}
+
+	e := example{tokens: learnTokens(r), tags: uniq(r.Tags)}
+	l.examples[r.ID] = e
+	for _, token := range e.tokens {
+		l.tokenDocs[token]++
+	}
+	for _, tag := range e.tags {
+		l.tagDocs[tag]++
+		if l.tagTokens[tag] == nil {
+			l.tagTokens[tag] = make(map[string]int)
+		}
+		for _, token := range e.tokens {
+			l.tagTokens[tag][token]++
+		}
+	}
+}
+
+// Forget forgets the repository id.
+func (l *Learner) Forget(id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.forget(id)
+}
+
+func (l *Learner) forget(id int) {
+	e, ok := l.examples[id]
+	if !ok {
+		return
+	}
+	delete(l.examples, id)
+	for _, token := range e.tokens {
+		if l.tokenDocs[token]--; l.tokenDocs[token] == 0 {
+			delete(l.tokenDocs, token)
+		}
+	}
+	for _, tag := range e.tags {
+		if l.tagDocs[tag]--; l.tagDocs[tag] == 0 {
+			delete(l.tagDocs, tag)
+			delete(l.tagTokens, tag)
+			continue
+		}
+		for _, token := range e.tokens {
+			if l.tagTokens[tag][token]--; l.tagTokens[tag][token] == 0 {
+				delete(l.tagTokens[tag], token)
+			}
+		}
+	}
+}
+
+// Suggest suggests the tags r does not have yet whose
+// probability is at least minScore, the most probable
+// first. The Score is the probability of the tag.
+func (l *Learner) Suggest(r *Repo, minScore float64) []Suggestion {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	has := make(map[string]bool, len(r.Tags))
+	for _, tag := range r.Tags {
+		has[tag] = true
+	}
+	tokens := learnTokens(r)
+	docs := float64(len(l.examples))
+
+	suggestions := make([]Suggestion, 0)
+	for tag, n := range l.tagDocs {
+		if has[tag] || n < minTagDocs {
+			continue
+		}
+		// log odds of the tag with laplace smoothing.
+		with, without := float64(n), docs-float64(n)
+		logOdds := math.Log((with + 1) / (without + 1))
+		for _, token := range tokens {
+			inTag := float64(l.tagTokens[tag][token])
+			notInTag := float64(l.tokenDocs[token]) - inTag
+			logOdds += math.Log((inTag+1)/(with+2)) - math.Log((notInTag+1)/(without+2))
+		}
+		score := 1 / (1 + math.Exp(-logOdds))
+		if score < minScore {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{
+			Tag:    tag,
+			Score:  score,
+			Rule:   "learner",
+			Reason: fmt.Sprintf("learned from %d repositories tagged %s", n, tag),
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	return suggestions
+}
+
+// learnTokens returns the tokens of r, its description
+// words, its language and its topics, without duplicates.
+func learnTokens(r *Repo) []string {
+	tokens := make([]string, 0)
+	for _, w := range strings.FieldsFunc(strings.ToLower(r.Desc), notWord) {
+		if len(w) > 2 && !stopwords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	if r.Lang != "" {
+		tokens = append(tokens, "lang:"+strings.ToLower(r.Lang))
+	}
+	for _, topic := range r.Topics {
+		tokens = append(tokens, "topic:"+strings.ToLower(topic))
+	}
+	return uniq(tokens)
+}
+
+func uniq(ss []string) []string {
+	seen := make(map[string]bool, len(ss))
+	u := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			u = append(u, s)
+		}
+	}
+	return u
+}
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLearner(t *testing.T) {
+	l := NewLearner()
+	tagged := []*Repo{
+		{ID: 1, Desc: "HTTP router for web services", Lang: "Go", Tags: []string{"web"}},
+		{ID: 2, Desc: "Fast web framework", Lang: "Go", Tags: []string{"web"}},
+		{ID: 3, Desc: "Terminal file manager", Lang: "Rust", Tags: []string{"cli"}},
+		{ID: 4, Desc: "Command line parser", Lang: "Rust", Topics: []string{"terminal"},
+			Tags: []string{"cli"}},
+		{ID: 5, Desc: "Untagged web thing"},
+	}
+	for _, r := range tagged {
+		l.Learn(r)
+	}
+
+	r := &Repo{ID: 6, Desc: "A tiny web router", Lang: "Go"}
+	got := l.Suggest(r, 0.5)
+	if len(got) != 1 || got[0].Tag != "web" || got[0].Score < 0.9 {
+		t.Fatalf("expected web suggestion; got %+v", got)
+	}
+
+	r = &Repo{ID: 6, Desc: "terminal dashboard", Lang: "Rust"}
+	got = l.Suggest(r, 0.5)
+	if len(got) != 1 || got[0].Tag != "cli" {
+		t.Fatalf("expected cli suggestion; got %+v", got)
+	}
+
+	// the tags of the repository are not suggested.
+	r.Tags = []string{"cli"}
+	if got := l.Suggest(r, 0.5); len(got) != 0 {
+		t.Fatalf("expected no suggestions; got %+v", got)
+	}
+
+	// retagged repos replace what was learned.
+	l.Learn(&Repo{ID: 3, Desc: "Terminal file manager", Lang: "Rust", Tags: []string{"tui"}})
+	l.Forget(4)
+	if got := l.Suggest(&Repo{Desc: "terminal dashboard", Lang: "Rust"}, 0); len(got) != 1 ||
+		got[0].Tag != "web" {
+		t.Fatalf("expected only web suggestion; got %+v", got)
+	}
+}
+
+func TestLearnTokens(t *testing.T) {
+	r := &Repo{Desc: "A simple library for parsing the command line", Lang: "Go",
+		Topics: []string{"CLI"}}
+	tokens := learnTokens(r)
+	expected := []string{"parsing", "command", "line", "lang:go", "topic:cli"}
+	if fmt.Sprint(tokens) != fmt.Sprint(expected) {
+		t.Fatalf("expected tokens %v; got %v", expected, tokens)
+	}
+}
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// link is a link of a Link header, RFC 8288.
+type link struct {
+	url    string
+	params map[string]string
+}
+
+// parseLinks parses the values of the Link header and
+// returns the URLs by relation type, like "next" and
+// "last". Relation types are lower case.
+func parseLinks(header http.Header) map[string]string {
+	rels := make(map[string]string)
+	for _, v := range header.Values("Link") {
+		for _, l := range parseLinkValue(v) {
+			for _, rel := range strings.Fields(l.params["rel"]) {
+				rel = strings.ToLower(rel)
+				// the first link of a relation wins.
+				if _, ok := rels[rel]; !ok {
+					rels[rel] = l.url
+				}
+			}
+		}
+	}
+	return rels
+}
+
+// parseLinkValue parses a Link header value, a comma separated
+// list of <URI-Reference> followed by ;param=value parameters.
+// Values can be tokens or quoted strings. Invalid links are
+// skipped.
+func parseLinkValue(s string) []link {
+	links := make([]link, 0)
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			return links
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			return links
+		}
+		l := link{url: s[start+1 : start+end], params: make(map[string]string)}
+		s = s[start+end+1:]
+
+		// parameters until the comma of the next link.
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if s == "" || s[0] != ';' {
+				break
+			}
+			var name, value string
+			name, value, s = parseLinkParam(s[1:])
+			if name == "" {
+				continue
+			}
+			// the first occurrence of a parameter wins.
+			if _, ok := l.params[name]; !ok {
+				l.params[name] = value
+			}
+		}
+		links = append(links, l)
+
+		comma := strings.IndexByte(s, ',')
+		if comma < 0 {
+			return links
+		}
+		s = s[comma+1:]
+	}
+}
+
+// parseLinkParam parses a name=value parameter at the start of s
+// and returns the lower case name, the value and the rest of s.
+func parseLinkParam(s string) (name, value, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	i := strings.IndexAny(s, "=;,")
+	if i < 0 {
+		return strings.ToLower(strings.TrimSpace(s)), "", ""
+	}
+	name = strings.ToLower(strings.TrimSpace(s[:i]))
+	if s[i] != '=' {
+		// parameter without value.
+		return name, "", s[i:]
+	}
+	s = strings.TrimLeft(s[i+1:], " \t")
+
+	if s == "" || s[0] != '"' {
+		end := strings.IndexAny(s, ";,")
+		if end < 0 {
+			return name, strings.TrimSpace(s), ""
+		}
+		return name, strings.TrimSpace(s[:end]), s[end:]
+	}
+
+	// quoted string with backslash escapes.
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == '"':
+			return name, b.String(), s[i+1:]
+		default:
+			b.WriteByte(c)
+		}
+	}
+	// unterminated quoted string.
+	return name, b.String(), ""
+}
+
+// pageOf returns the page query parameter of rawURL or
+// 0 if there is none.
+func pageOf(rawURL string) int {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	// discard error, 0 will be treated as error.
+	page, _ := strconv.Atoi(u.Query().Get("page"))
+	return page
+}
@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	tt := []struct {
+		name   string
+		values []string
+		rels   map[string]string
+	}{
+		{"none", nil, map[string]string{}},
+		{"github", []string{`<https://api.github.com/user/0/starred?page=2>; rel="next",
+    						<https://api.github.com/user/0/starred?page=500>; rel="last"`},
+			map[string]string{
+				"next": "https://api.github.com/user/0/starred?page=2",
+				"last": "https://api.github.com/user/0/starred?page=500",
+			}},
+		{"token and case", []string{`<http://a/2>;REL=Next`},
+			map[string]string{"next": "http://a/2"}},
+		{"many rels", []string{`<http://a/2>; rel="next last"`},
+			map[string]string{"next": "http://a/2", "last": "http://a/2"}},
+		{"comma in url", []string{`<http://a/?q=a,b;c>; rel="next", <http://a/9>; rel="last"`},
+			map[string]string{"next": "http://a/?q=a,b;c", "last": "http://a/9"}},
+		{"other params", []string{`<http://a/2>; title="a, \"b\"; c"; rel=next; anchor="#x"`},
+			map[string]string{"next": "http://a/2"}},
+		{"many values", []string{`<http://a/1>; rel="prev"`, `<http://a/3>; rel="next"`},
+			map[string]string{"prev": "http://a/1", "next": "http://a/3"}},
+		{"first wins", []string{`<http://a/2>; rel="next", <http://a/3>; rel="next"`},
+			map[string]string{"next": "http://a/2"}},
+		{"no rel", []string{`<http://a/2>; title="next"`}, map[string]string{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{"Link": tc.values}
+			rels := parseLinks(h)
+			if len(rels) != len(tc.rels) {
+				t.Fatalf("expected %v; got %v", tc.rels, rels)
+			}
+			for rel, u := range tc.rels {
+				if rels[rel] != u {
+					t.Fatalf("expected %s link %q; got %q", rel, u, rels[rel])
+				}
+			}
+		})
+	}
+}
+
+func TestFollowNext(t *testing.T) {
+	var requests []string
+	var url string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		page := 1
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		// the last page is unknown.
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/next?page=%d>; rel="next"`, url, page+1))
+		}
+		fmt.Fprintf(w, `[{"id": %d}]`, page)
+	}))
+	defer s.Close()
+	url = s.URL
+
+	g := &GitHub{}
+	rs, err := g.getRepos(context.Background(), s.URL+"/starred?per_page=100&page=%d")
+	if err != nil {
+		t.Fatalf("failed to get repos: %v", err)
+	}
+	if len(rs) != 3 {
+		t.Fatalf("expected 3 repos; got %d", len(rs))
+	}
+	expected := []string{"/starred?per_page=100&page=1", "/next?page=2", "/next?page=3"}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Fatalf("expected requests %v; got %v", expected, requests)
+	}
+}
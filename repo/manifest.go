@@ -0,0 +1,441 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Manifest is the dependencies declared in a manifest file.
+type Manifest struct {
+	// File is the name of the file, like go.mod.
+	File string
+	Deps []string
+	// Bin reports whether the manifest declares executables.
+	Bin bool
+}
+
+// manifestParsers are the parsers of each manifest file.
+var manifestParsers = map[string]func(data []byte) (*Manifest, error){
+	"go.mod":           parseGoMod,
+	"package.json":     parsePackageJSON,
+	"requirements.txt": parseRequirements,
+	"pyproject.toml":   parsePyproject,
+	"Cargo.toml":       parseCargo,
+	"Gemfile":          parseGemfile,
+	"pom.xml":          parsePom,
+}
+
+// IsManifest reports whether ParseManifest can parse file.
+func IsManifest(file string) bool {
+	_, ok := manifestParsers[path.Base(file)]
+	return ok
+}
+
+// ParseManifest parses the manifest file with content data.
+func ParseManifest(file string, data []byte) (*Manifest, error) {
+	parse, ok := manifestParsers[path.Base(file)]
+	if !ok {
+		return nil, fmt.Errorf("unknown manifest %q", file)
+	}
+	m, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	m.File = path.Base(file)
+	return m, nil
+}
+
+// lines calls f with each line of data without
+// spaces around and comments started by comment.
+func lines(data []byte, comment string, f func(line string)) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, comment); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			f(line)
+		}
+	}
+}
+
+func parseGoMod(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	inRequire := false
+	lines(data, "//", func(line string) {
+		fields := strings.Fields(line)
+		switch {
+		// any spaces, like require ( or require(.
+		case strings.Join(fields, "") == "require(":
+			inRequire = true
+		case inRequire && line == ")":
+			inRequire = false
+		case inRequire:
+			m.Deps = append(m.Deps, fields[0])
+		case fields[0] == "require" && len(fields) > 1:
+			m.Deps = append(m.Deps, fields[1])
+		}
+	})
+	return m, nil
+}
+
+func parsePackageJSON(data []byte) (*Manifest, error) {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+		// Bin is a path or a map of names to paths.
+		Bin json.RawMessage `json:"bin"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, err
+	}
+	m := &Manifest{Bin: len(pkg.Bin) > 0 && string(pkg.Bin) != "null"}
+	for dep := range pkg.Dependencies {
+		m.Deps = append(m.Deps, dep)
+	}
+	for dep := range pkg.DevDependencies {
+		m.Deps = append(m.Deps, dep)
+	}
+	sort.Strings(m.Deps)
+	return m, nil
+}
+
+// pythonName returns the normalized name of the
+// python requirement req, like "Django>=3.0".
+func pythonName(req string) string {
+	req = strings.TrimSpace(strings.Trim(strings.TrimSpace(req), `"',`))
+	if i := strings.IndexAny(req, "<>=!~;[@ "); i >= 0 {
+		req = req[:i]
+	}
+	return strings.Replace(strings.ToLower(req), "_", "-", -1)
+}
+
+func parseRequirements(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	lines(data, "#", func(line string) {
+		// options like -r other.txt or -e .
+		if strings.HasPrefix(line, "-") {
+			return
+		}
+		if name := pythonName(line); name != "" {
+			m.Deps = append(m.Deps, name)
+		}
+	})
+	return m, nil
+}
+
+// tomlSections calls f with each key = value line of data
+// and the name of its section, like dependencies.
+func tomlSections(data []byte, f func(section, key, value string)) {
+	section := ""
+	// the key of a multiline array.
+	arrayKey := ""
+	lines(data, "#", func(line string) {
+		if arrayKey != "" {
+			if strings.HasPrefix(line, "]") {
+				arrayKey = ""
+				return
+			}
+			f(section, arrayKey, line)
+			return
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			return
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return
+		}
+		key := strings.Trim(strings.TrimSpace(line[:i]), `"`)
+		value := strings.TrimSpace(line[i+1:])
+		if value == "[" {
+			arrayKey = key
+			return
+		}
+		// an inline array.
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			for _, v := range strings.Split(strings.Trim(value, "[]"), ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					f(section, key, v)
+				}
+			}
+			return
+		}
+		f(section, key, value)
+	})
+}
+
+func parsePyproject(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	tomlSections(data, func(section, key, value string) {
+		switch {
+		case section == "project" && key == "dependencies":
+			m.Deps = append(m.Deps, pythonName(value))
+		case section == "tool.poetry.dependencies" && key != "python":
+			m.Deps = append(m.Deps, pythonName(key))
+		case section == "project.scripts" || section == "tool.poetry.scripts":
+			m.Bin = true
+		}
+	})
+	return m, nil
+}
+
+func parseCargo(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	seen := make(map[string]bool)
+	add := func(dep string) {
+		if !seen[dep] {
+			seen[dep] = true
+			m.Deps = append(m.Deps, dep)
+		}
+	}
+	sections := []string{"dependencies", "dev-dependencies", "build-dependencies"}
+	tomlSections(data, func(section, key, value string) {
+		for _, s := range sections {
+			if section == s {
+				add(key)
+			}
+			// a table like [dependencies.serde].
+			if strings.HasPrefix(section, s+".") {
+				add(section[len(s)+1:])
+			}
+		}
+	})
+	// [[bin]] has no keys before the next section.
+	m.Bin = bytes.Contains(data, []byte("[[bin]]"))
+	return m, nil
+}
+
+func parseGemfile(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	lines(data, "#", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "gem" {
+			return
+		}
+		m.Deps = append(m.Deps, strings.Trim(fields[1], `"',`))
+	})
+	return m, nil
+}
+
+func parsePom(data []byte) (*Manifest, error) {
+	type dependency struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+	}
+	var pom struct {
+		Parent       dependency   `xml:"parent"`
+		Dependencies []dependency `xml:"dependencies>dependency"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	deps := pom.Dependencies
+	if pom.Parent.ArtifactID != "" {
+		deps = append([]dependency{pom.Parent}, deps...)
+	}
+	for _, d := range deps {
+		m.Deps = append(m.Deps, d.GroupID+":"+d.ArtifactID)
+	}
+	return m, nil
+}
+
+// depTags are the tags suggested by the dependencies of each
+// manifest. A dependency ending with / or : matches all the
+// dependencies with its prefix.
+var depTags = map[string]map[string]string{
+	"go.mod": {
+		"github.com/gin-gonic/gin":    "uses-gin",
+		"github.com/labstack/echo":    "uses-echo",
+		"github.com/gofiber/fiber":    "uses-fiber",
+		"github.com/gorilla/mux":      "uses-gorilla",
+		"github.com/spf13/cobra":      "cli",
+		"github.com/urfave/cli":       "cli",
+		"google.golang.org/grpc":      "grpc",
+		"gorm.io/gorm":                "orm",
+		"k8s.io/":                     "kubernetes",
+		"github.com/docker/docker":    "docker",
+		"github.com/mattn/go-sqlite3": "sqlite",
+	},
+	"package.json": {
+		"react":      "react",
+		"vue":        "vue",
+		"@angular/":  "angular",
+		"svelte":     "svelte",
+		"next":       "nextjs",
+		"express":    "uses-express",
+		"electron":   "electron",
+		"commander":  "cli",
+		"yargs":      "cli",
+		"typescript": "typescript",
+	},
+	"requirements.txt": pythonTags,
+	"pyproject.toml":   pythonTags,
+	"Cargo.toml": {
+		"clap":         "cli",
+		"tokio":        "async",
+		"actix-web":    "uses-actix",
+		"rocket":       "uses-rocket",
+		"wasm-bindgen": "wasm",
+		"bevy":         "game",
+	},
+	"Gemfile": {
+		"rails":   "rails",
+		"sinatra": "uses-sinatra",
+		"thor":    "cli",
+		"rspec":   "rspec",
+	},
+	"pom.xml": {
+		"org.springframework.boot:": "spring",
+		"io.quarkus:":               "quarkus",
+		"junit:":                    "junit",
+		"org.junit.jupiter:":        "junit",
+	},
+}
+
+var pythonTags = map[string]string{
+	"django":       "django",
+	"flask":        "flask",
+	"fastapi":      "fastapi",
+	"numpy":        "data-science",
+	"pandas":       "data-science",
+	"torch":        "machine-learning",
+	"tensorflow":   "machine-learning",
+	"scikit-learn": "machine-learning",
+	"click":        "cli",
+	"typer":        "cli",
+}
+
+// depMatch reports whether dep matches the dependency key
+// of depTags, Go modules also match their major versions.
+func depMatch(dep, key string) bool {
+	if strings.HasSuffix(key, "/") || strings.HasSuffix(key, ":") {
+		return strings.HasPrefix(dep, key)
+	}
+	return dep == key || strings.HasPrefix(dep, key+"/")
+}
+
+// Scores of the manifest suggestions, a dependency can
+// be only used by the tests or examples.
+const (
+	depScore = 0.9
+	binScore = 0.8
+)
+
+// SuggestManifest suggests tags from the dependencies of m, a
+// manifest with executables suggests cli.
+func SuggestManifest(m *Manifest) []Suggestion {
+	suggestions := make([]Suggestion, 0)
+	seen := make(map[string]bool)
+	add := func(tag string, score float64, reason string) {
+		if !seen[tag] {
+			seen[tag] = true
+			suggestions = append(suggestions, Suggestion{Tag: tag, Score: score,
+				Rule: "manifest/" + m.File, Reason: reason})
+		}
+	}
+
+	tags := depTags[m.File]
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, dep := range m.Deps {
+		for _, key := range keys {
+			if depMatch(dep, key) {
+				add(tags[key], depScore, m.File+" depends on "+dep)
+			}
+		}
+	}
+	if m.Bin {
+		add("cli", binScore, m.File+" declares executables")
+	}
+	return suggestions
+}
+
+// ManifestFetcher is a Source that can fetch manifest files.
+type ManifestFetcher interface {
+	Source
+	Manifests(ctx context.Context, r *Repo) ([]*Manifest, error)
+}
+
+var _ ManifestFetcher = (*GitHub)(nil)
+
+// ManifestErr is returned along with the parsed manifests
+// when some manifest files failed, it has the error of each
+// file.
+type ManifestErr map[string]error
+
+func (e ManifestErr) Error() string {
+	files := make([]string, 0, len(e))
+	for file := range e {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	ss := make([]string, len(files))
+	for i, file := range files {
+		ss[i] = file + ": " + e[file].Error()
+	}
+	return fmt.Sprintf("failed to read %d manifests: %s", len(e), strings.Join(ss, "; "))
+}
+
+// Manifests fetches and parses the manifest files in the root
+// directory of r. An empty or missing repository returns
+// NotFoundErr, the manifest files that failed return ManifestErr
+// along with the others.
+func (g *GitHub) Manifests(ctx context.Context, r *Repo) ([]*Manifest, error) {
+	url := g.baseURL() + "/repos/" + fullName(r) + "/contents/"
+	res, err := g.requestPage(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		var notFound NotFoundErr
+		return nil, notFound
+	}
+	if res.StatusCode != 200 {
+		return nil, statusErr(res)
+	}
+	var files []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&files); err != nil {
+		return nil, err
+	}
+
+	manifests := make([]*Manifest, 0)
+	failed := make(ManifestErr)
+	for _, f := range files {
+		if f.Type != "file" || !IsManifest(f.Name) {
+			continue
+		}
+		_, data, err := g.getContent(ctx, url+f.Name, nil)
+		if err != nil {
+			failed[f.Name] = err
+			continue
+		}
+		m, err := ParseManifest(f.Name, data)
+		if err != nil {
+			failed[f.Name] = err
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	if len(failed) > 0 {
+		return manifests, failed
+	}
+	return manifests, nil
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tt := []struct {
+		file string
+		deps []string
+		bin  bool
+		tags []string
+	}{
+		{"go.mod", []string{"github.com/spf13/cobra", "github.com/gin-gonic/gin",
+			"github.com/urfave/cli/v2", "k8s.io/client-go"}, false,
+			[]string{"cli", "uses-gin", "kubernetes"}},
+		{"package.json", []string{"@angular/core", "react", "typescript"}, true,
+			[]string{"angular", "react", "typescript", "cli"}},
+		{"requirements.txt", []string{"django", "numpy", "scikit-learn"}, false,
+			[]string{"django", "data-science", "machine-learning"}},
+		{"pyproject.toml", []string{"fastapi", "typer", "flask"}, true,
+			[]string{"fastapi", "cli", "flask"}},
+		{"Cargo.toml", []string{"clap", "tokio", "serde"}, true, []string{"cli", "async"}},
+		{"Gemfile", []string{"rails", "rspec"}, false, []string{"rails", "rspec"}},
+		{"pom.xml", []string{"org.springframework.boot:spring-boot-starter-parent",
+			"junit:junit"}, false, []string{"spring", "junit"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", "manifests", tc.file))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			m, err := ParseManifest(tc.file, data)
+			if err != nil {
+				t.Fatalf("failed to parse manifest: %v", err)
+			}
+			if m.File != tc.file || m.Bin != tc.bin || !reflect.DeepEqual(m.Deps, tc.deps) {
+				t.Fatalf("expected deps %v and bin %t; got %+v", tc.deps, tc.bin, m)
+			}
+
+			suggestions := SuggestManifest(m)
+			tags := make([]string, len(suggestions))
+			for i, s := range suggestions {
+				tags[i] = s.Tag
+				if s.Reason == "" {
+					t.Errorf("suggestion %s has no reason", s.Tag)
+				}
+			}
+			if !reflect.DeepEqual(tags, tc.tags) {
+				t.Fatalf("expected tags %v; got %v", tc.tags, tags)
+			}
+		})
+	}
+
+	if _, err := ParseManifest("build.gradle", nil); err == nil {
+		t.Errorf("expected error of unknown manifest")
+	}
+	if _, err := ParseManifest("package.json", []byte("{")); err == nil {
+		t.Errorf("expected error of invalid manifest")
+	}
+}
+
+func TestManifests(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/foo/bar/contents/":
+			fmt.Fprint(w, `[{"name": "README.md", "type": "file"}, {"name": "go.mod", "type": "file"},
+				{"name": "Gemfile", "type": "dir"}, {"name": "package.json", "type": "file"}]`)
+		case "/repos/foo/bar/contents/package.json":
+			content := base64.StdEncoding.EncodeToString([]byte("{"))
+			fmt.Fprintf(w, `{"sha": "def", "encoding": "base64", "content": "%s"}`, content)
+		case "/repos/foo/bar/contents/go.mod":
+			content := base64.StdEncoding.EncodeToString([]byte("require github.com/spf13/cobra v1"))
+			fmt.Fprintf(w, `{"sha": "abc", "encoding": "base64", "content": "%s"}`, content)
+		default:
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	g := &GitHub{BaseURL: s.URL}
+	ms, err := g.Manifests(context.Background(), &Repo{FullName: "foo/bar"})
+	// the invalid package.json does not fail go.mod.
+	mErr, ok := err.(ManifestErr)
+	if !ok || len(mErr) != 1 || mErr["package.json"] == nil {
+		t.Fatalf("expected error of package.json; got %v", err)
+	}
+	if len(ms) != 1 || ms[0].File != "go.mod" ||
+		!reflect.DeepEqual(ms[0].Deps, []string{"github.com/spf13/cobra"}) {
+		t.Fatalf("got wrong manifests: %+v", ms)
+	}
+
+	_, err = g.Manifests(context.Background(), &Repo{FullName: "foo/empty"})
+	if err != NotFoundErr(0) {
+		t.Fatalf("expected not found; got %v", err)
+	}
+}
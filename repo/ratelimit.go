@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRetries is the number of times a request is
+	// retried before giving up.
+	maxRetries = 5
+	// maxPause is the longest wait for the rate limit
+	// reset, a later reset returns RateLimitErr at once.
+	maxPause = time.Minute
+)
+
+var (
+	// backoff is the wait before the first retry, it
+	// doubles on each retry.
+	backoff = time.Second
+	// sleep is replaced by tests to not wait.
+	sleep = sleepContext
+)
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateLimitErr is returned when GitHub still rejects the
+// requests after all retries. Its value is the time left
+// to the rate limit reset.
+type RateLimitErr time.Duration
+
+func (e RateLimitErr) Error() string {
+	return fmt.Sprintf("rate limit exceeded, reset in %s", time.Duration(e).Round(time.Second))
+}
+
+// rateLimitWait returns how long to wait before retrying
+// a request and if res was rejected by the rate limit.
+func rateLimitWait(res *http.Response) (time.Duration, bool) {
+	if res.StatusCode != http.StatusForbidden &&
+		res.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if after := res.Header.Get("Retry-After"); after != "" {
+		sec, err := strconv.Atoi(after)
+		if err == nil {
+			return time.Duration(sec) * time.Second, true
+		}
+	}
+
+	if res.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, true
+	}
+	return time.Until(time.Unix(reset, 0)), true
+}
+
+// RateLimit is the rate limit of a token, it has the
+// time until all its requests are paused.
+type RateLimit struct {
+	mu     sync.Mutex
+	resume time.Time
+}
+
+// rateLimit returns the RateLimit of g.
+func (g *GitHub) rateLimit() *RateLimit {
+	if g.RateLimit != nil {
+		return g.RateLimit
+	}
+	return &g.limit
+}
+
+// pause stops the requests of g for d.
+func (g *GitHub) pause(ctx context.Context, d time.Duration) error {
+	rl := g.rateLimit()
+	rl.mu.Lock()
+	if resume := time.Now().Add(d); resume.After(rl.resume) {
+		rl.resume = resume
+	}
+	rl.mu.Unlock()
+	return g.waitResume(ctx)
+}
+
+// waitResume waits until the pause of g ends or ctx is done.
+func (g *GitHub) waitResume(ctx context.Context) error {
+	rl := g.rateLimit()
+	rl.mu.Lock()
+	d := time.Until(rl.resume)
+	rl.mu.Unlock()
+	if d > 0 {
+		return sleep(ctx, d)
+	}
+	return ctx.Err()
+}
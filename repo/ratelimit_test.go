@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// throttleServer rejects the first n requests.
+type throttleServer struct {
+	mu       sync.Mutex
+	n        int
+	requests int
+	reject   func(w http.ResponseWriter)
+}
+
+func (ts *throttleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	ts.requests++
+	reject := ts.requests <= ts.n
+	ts.mu.Unlock()
+
+	if reject {
+		ts.reject(w)
+		return
+	}
+	w.Write([]byte(`[{"id": 1, "name": "foo"}]`))
+}
+
+// fakeSleep replaces sleep and records the durations.
+func fakeSleep(t *testing.T) *[]time.Duration {
+	var mu sync.Mutex
+	slept := make([]time.Duration, 0)
+	old := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		slept = append(slept, d)
+		mu.Unlock()
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = old })
+	return &slept
+}
+
+func TestRateLimit(t *testing.T) {
+	tt := []struct {
+		name     string
+		n        int
+		reject   func(w http.ResponseWriter)
+		minSleep time.Duration
+		err      bool
+	}{
+		{"retry after", 1, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, 29 * time.Second, false},
+		{"reset", 1, func(w http.ResponseWriter) {
+			reset := time.Now().Add(50 * time.Second).Unix()
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			w.WriteHeader(http.StatusForbidden)
+		}, 45 * time.Second, false},
+		// the reset is too far to wait.
+		{"late reset", 1, func(w http.ResponseWriter) {
+			reset := time.Now().Add(time.Hour).Unix()
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			w.WriteHeader(http.StatusForbidden)
+		}, 0, true},
+		{"server error", 2, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadGateway)
+		}, 2 * time.Second, false},
+		{"give up", maxRetries + 1, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, 0, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			slept := fakeSleep(t)
+			ts := &throttleServer{n: tc.n, reject: tc.reject}
+			s := httptest.NewServer(ts)
+			defer s.Close()
+
+			g := &GitHub{}
+			rs, err := g.getRepos(context.Background(), s.URL+"/%d")
+			if tc.err {
+				if _, ok := err.(RateLimitErr); !ok {
+					t.Fatalf("expected rate limit error; got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get repos: %v", err)
+			}
+			if len(rs) != 1 {
+				t.Fatalf("expected 1 repo; got %d", len(rs))
+			}
+
+			var max time.Duration
+			for _, d := range *slept {
+				if d > max {
+					max = d
+				}
+			}
+			if max < tc.minSleep {
+				t.Fatalf("expected to wait at least %s; got %v", tc.minSleep, *slept)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	fakeSleep(t)
+	ts := &testServer{t}
+	s := httptest.NewServer(http.HandlerFunc(ts.respJSON))
+	defer s.Close()
+
+	var mu sync.Mutex
+	last, calls := 0, 0
+	g := &GitHub{Progress: func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if total != 500 {
+			t.Errorf("expected 500 pages; got %d", total)
+		}
+		last = done
+	}}
+	if _, err := g.getRepos(context.Background(), s.URL+"/%d"); err != nil {
+		t.Fatalf("failed to get repos: %v", err)
+	}
+	if calls != 500 || last != 500 {
+		t.Fatalf("expected 500 progress calls ending at 500; got %d ending at %d", calls, last)
+	}
+}
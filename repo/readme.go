@@ -0,0 +1,188 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Readme is the README of a repository as plain text.
+type Readme struct {
+	// SHA is the blob sha of the README, it
+	// changes only when the README changes.
+	SHA  string `json:"sha"`
+	Text string `json:"text"`
+	// ETag is the ETag of the response of the README,
+	// it is sent to fetch the README only if it changed.
+	ETag string `json:"etag,omitempty"`
+}
+
+// ReadmeFetcher is a Source that can fetch READMEs.
+type ReadmeFetcher interface {
+	Source
+	Readme(ctx context.Context, r *Repo, old *Readme) (*Readme, error)
+}
+
+var _ ReadmeFetcher = (*GitHub)(nil)
+
+// githubContent is a file of the contents API.
+type githubContent struct {
+	SHA      string `json:"sha"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+	// ETag is the ETag header of the response.
+	ETag string `json:"-"`
+}
+
+// errNotModified is returned by getContent if
+// the file did not change since its ETag.
+var errNotModified = errors.New("not modified")
+
+// Readme fetches the README of r through the contents API and
+// strips its markdown. A repository without README returns
+// NotFoundErr. The README is not downloaded again if it is
+// the same as old, that is returned instead.
+func (g *GitHub) Readme(ctx context.Context, r *Repo, old *Readme) (*Readme, error) {
+	var header http.Header
+	if old != nil && old.ETag != "" {
+		header = http.Header{"If-None-Match": {old.ETag}}
+	}
+	c, data, err := g.getContent(ctx, g.baseURL()+"/repos/"+fullName(r)+"/readme", header)
+	if err == errNotModified {
+		return old, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Readme{SHA: c.SHA, Text: StripMarkdown(string(data)), ETag: c.ETag}, nil
+}
+
+// getContent fetches and decodes a file of the contents API.
+func (g *GitHub) getContent(ctx context.Context, url string, header http.Header) (*githubContent, []byte, error) {
+	res, err := g.requestPage(ctx, url, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 304:
+		return nil, nil, errNotModified
+	case 404:
+		var notFound NotFoundErr
+		return nil, nil, notFound
+	}
+	if res.StatusCode != 200 {
+		return nil, nil, statusErr(res)
+	}
+
+	c := &githubContent{ETag: res.Header.Get("ETag")}
+	if err := json.NewDecoder(res.Body).Decode(c); err != nil {
+		return nil, nil, err
+	}
+	if c.Encoding != "base64" {
+		return nil, nil, fmt.Errorf("unexpected content encoding %q", c.Encoding)
+	}
+	// the content is split in lines.
+	data, err := base64.StdEncoding.DecodeString(strings.Replace(c.Content, "\n", "", -1))
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, data, nil
+}
+
+// markdown is what StripMarkdown replaces, in order.
+var markdown = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// code blocks and html comments.
+	{regexp.MustCompile("(?s)```.*?```|~~~.*?~~~|<!--.*?-->"), ""},
+	// images and links keep their text, images
+	// first as they can be inside links.
+	{regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`), "$1"},
+	{regexp.MustCompile(`!?\[([^\]]*)\]\[[^\]]*\]`), "$1"},
+	// reference definitions.
+	{regexp.MustCompile(`(?m)^\s*\[[^\]]+\]:\s*\S+.*$`), ""},
+	{regexp.MustCompile(`<[^>]+>`), ""},
+	// headings, quotes and lists.
+	{regexp.MustCompile(`(?m)^\s*(#{1,6}|>+|[-*+]|\d+\.)\s+`), ""},
+	// rules and table separators.
+	{regexp.MustCompile(`(?m)^\s*([-*_=|:]\s*){3,}$`), ""},
+	{regexp.MustCompile("[*_`~|]+"), " "},
+	{regexp.MustCompile(`[ \t]+`), " "},
+	{regexp.MustCompile(`(?m)^ | $`), ""},
+	{regexp.MustCompile(`\n\s*\n+`), "\n"},
+}
+
+// StripMarkdown returns the text of the markdown md, without
+// code blocks, html, link targets and formatting.
+func StripMarkdown(md string) string {
+	text := strings.Replace(md, "\r\n", "\n", -1)
+	for _, m := range markdown {
+		text = m.re.ReplaceAllString(text, m.repl)
+	}
+	return strings.TrimSpace(text)
+}
+
+// readmeKeywords are the keywords of the tags
+// suggested by SuggestReadme.
+var readmeKeywords = map[string][]string{
+	"cli":              {"cli", "command line", "command-line", "terminal"},
+	"docker":           {"docker", "dockerfile", "container", "containers"},
+	"kubernetes":       {"kubernetes", "k8s", "helm"},
+	"database":         {"database", "sql", "postgres", "postgresql", "mysql", "sqlite"},
+	"web-framework":    {"web framework", "http router", "middleware"},
+	"api":              {"api", "rest", "graphql", "grpc"},
+	"machine-learning": {"machine learning", "neural network", "deep learning"},
+	"testing":          {"testing", "test framework", "mock", "mocks"},
+	"security":         {"security", "encryption", "vulnerability", "authentication"},
+	"library":          {"library", "package", "sdk"},
+	"game":             {"game", "game engine"},
+	"editor":           {"editor", "vim", "emacs", "vscode"},
+}
+
+// SuggestReadme suggests tags whose keywords appear in the
+// README text, the most frequent first. Only the tags with
+// more than one mention are suggested, the score grows with
+// the mentions.
+func SuggestReadme(text string) []Suggestion {
+	text = " " + strings.ToLower(strings.Join(strings.FieldsFunc(text, notWord), " ")) + " "
+	counts := make(map[string]int)
+	for tag, keywords := range readmeKeywords {
+		for _, k := range keywords {
+			counts[tag] += strings.Count(text, " "+k+" ")
+		}
+	}
+
+	suggestions := make([]Suggestion, 0)
+	for tag, n := range counts {
+		if n > 1 {
+			suggestions = append(suggestions, Suggestion{
+				Tag:    tag,
+				Score:  1 - 1/float64(n),
+				Rule:   "readme",
+				Reason: fmt.Sprintf("%d mentions of %s in the README", n, tag),
+			})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if counts[suggestions[i].Tag] != counts[suggestions[j].Tag] {
+			return counts[suggestions[i].Tag] > counts[suggestions[j].Tag]
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	return suggestions
+}
+
+// notWord reports whether c splits words, the
+// dash is kept for words like command-line.
+func notWord(c rune) bool {
+	return !(c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+}
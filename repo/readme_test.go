@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const readmeMD = `# Foo
+
+[![build](https://ci.example.com/badge.svg)](https://ci.example.com)
+
+Foo is a **command line** tool to manage ` + "`docker`" + ` containers.
+See the [docs](https://foo.dev/docs).
+
+<!-- hidden -->
+` + "```go\nfmt.Println(\"code\")\n```" + `
+
+- runs in the terminal
+- talks to the Docker API
+
+| a | b |
+|---|---|
+`
+
+func TestStripMarkdown(t *testing.T) {
+	got := StripMarkdown(readmeMD)
+	want := "Foo\nbuild\nFoo is a command line tool to manage docker containers.\n" +
+		"See the docs.\nruns in the terminal\ntalks to the Docker API\na b"
+	if got != want {
+		t.Fatalf("expected %q; got %q", want, got)
+	}
+}
+
+func TestSuggestReadme(t *testing.T) {
+	got := Tags(SuggestReadme(StripMarkdown(readmeMD)))
+	want := []string{"docker", "cli"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v; got %v", want, got)
+	}
+}
+
+func TestReadme(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString([]byte(readmeMD))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/foo/bar/readme" {
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		// the API splits the content in lines.
+		fmt.Fprintf(w, `{"sha": "abc", "encoding": "base64", "content": "%s\n%s"}`,
+			content[:20], content[20:])
+	}))
+	defer s.Close()
+
+	g := &GitHub{BaseURL: s.URL}
+	readme, err := g.Readme(context.Background(), &Repo{FullName: "foo/bar"}, nil)
+	if err != nil {
+		t.Fatalf("failed to get readme: %v", err)
+	}
+	if readme.SHA != "abc" || readme.Text != StripMarkdown(readmeMD) || readme.ETag != `"v1"` {
+		t.Fatalf("got wrong readme: %+v", readme)
+	}
+
+	// the unchanged README is not downloaded.
+	old := &Readme{SHA: "abc", Text: "old", ETag: `"v1"`}
+	readme, err = g.Readme(context.Background(), &Repo{FullName: "foo/bar"}, old)
+	if err != nil {
+		t.Fatalf("failed to get readme: %v", err)
+	}
+	if readme != old {
+		t.Fatalf("expected the old readme; got %+v", readme)
+	}
+
+	_, err = g.Readme(context.Background(), &Repo{FullName: "foo/baz"}, nil)
+	if err != NotFoundErr(0) {
+		t.Fatalf("expected not found; got %v", err)
+	}
+}
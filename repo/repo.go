@@ -1,30 +1,113 @@
 package repo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
+	"time"
 )
 
-var (
-	reMaxPage = regexp.MustCompile(`<https:\/\/api.github.com\/user\/(\d+)\/starred\?page=(\d+)>; rel="last"`)
-	client    = &http.Client{}
-)
+var client = &http.Client{}
+
+// DefaultGitHubURL is the URL of the GitHub API.
+const DefaultGitHubURL = "https://api.github.com"
+
+// GitHub requests repositories from the GitHub API.
+type GitHub struct {
+	// BaseURL is the URL of the API, like the /api/v3 URL of
+	// GitHub Enterprise Server. Empty means DefaultGitHubURL.
+	BaseURL string
+	// Token is a personal access token sent with every
+	// request. An empty Token makes anonymous requests.
+	Token string
+	// Progress, if not nil, is called after each page of
+	// repositories is fetched.
+	Progress func(done, total int)
+	// RateLimit, if not nil, is shared by the clients of
+	// the same token so all of them pause together. A
+	// client without it has its own.
+	RateLimit *RateLimit
+
+	limit RateLimit
+}
+
+// String describes g without revealing the token.
+func (g *GitHub) String() string {
+	if g.Token == "" {
+		return "GitHub{anonymous}"
+	}
+	return "GitHub{token: [redacted]}"
+}
 
 // Repo stores repository info.
 type Repo struct {
+	// ID is the id of the repository in the catalog,
+	// the same as SourceID for GitHub repositories.
 	ID      int      `json:"id"`
 	Name    string   `json:"name"`
 	Desc    string   `json:"description"`
 	URLHTTP string   `json:"html_url"`
 	Lang    string   `json:"language"`
 	Tags    []string `json:"tags,omitempty"`
+	// StarredAt is when the user starred the repository.
+	StarredAt time.Time `json:"starred_at"`
+	// Status is empty for starred repositories or
+	// one of StatusUnstarred and StatusArchived.
+	Status string `json:"status,omitempty"`
+	// Source is the name of the Source of the repository
+	// and SourceID the id of the repository there.
+	Source   string `json:"source"`
+	SourceID int    `json:"source_id"`
+	// Owner is the login of the owner and FullName
+	// the owner/name of the repository.
+	Owner    string `json:"owner"`
+	FullName string `json:"full_name"`
+	// Topics are the tags given by the maintainers in the
+	// source, they are kept apart from the user's Tags.
+	Topics []string `json:"topics,omitempty"`
+
+	// Collections are where the users have the repository,
+	// like starred or org.
+	Collections []string `json:"collections,omitempty"`
+
+	// SystemTags are the tags given by repoTagger, like
+	// the activity tags, they are kept apart from Tags.
+	SystemTags []string `json:"system_tags,omitempty"`
+
+	// Metadata of the source.
+	Stars    int  `json:"stargazers_count"`
+	Forks    int  `json:"forks_count"`
+	Archived bool `json:"archived"`
+	Fork     bool `json:"fork"`
+	// OpenIssues is the number of open issues, GitHub
+	// also counts the open pull requests.
+	OpenIssues int `json:"open_issues_count"`
+	// License is the key of the license, like mit.
+	License string `json:"license"`
+	// OwnerType is User or Organization.
+	OwnerType string    `json:"owner_type"`
+	CreatedAt time.Time `json:"created_at"`
+	PushedAt  time.Time `json:"pushed_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ReleasedAt is when the latest release was published,
+	// it is zero if it was not fetched or there is none.
+	ReleasedAt time.Time `json:"released_at"`
 }
 
+// Status of repositories no longer starred.
+const (
+	// StatusUnstarred repositories keep their
+	// tags and are found by search.
+	StatusUnstarred = "unstarred"
+	// StatusArchived repositories keep their
+	// tags but are not found by search.
+	StatusArchived = "archived"
+)
+
 // NotFoundErr is used to know when
 // repo is not found in GitHub.
 type NotFoundErr int
@@ -33,6 +116,29 @@ func (e NotFoundErr) Error() string {
 	return fmt.Sprintf("not found")
 }
 
+// PageErr is the error of a page that failed
+// to be fetched or decoded.
+type PageErr struct {
+	Page int
+	Err  error
+}
+
+func (e *PageErr) Error() string {
+	return fmt.Sprintf("page %d: %v", e.Page, e.Err)
+}
+
+// PartialErr is returned along with the repositories
+// of the successful pages when some pages failed.
+type PartialErr []*PageErr
+
+func (e PartialErr) Error() string {
+	ss := make([]string, len(e))
+	for i, pErr := range e {
+		ss[i] = pErr.Error()
+	}
+	return fmt.Sprintf("failed to fetch %d pages: %s", len(e), strings.Join(ss, "; "))
+}
+
 // SetTags discard the last tags slice and set
 // the new one.
 func (r *Repo) SetTags(tags ...string) {
@@ -51,30 +157,58 @@ func (r *Repo) SetTags(tags ...string) {
 	}
 }
 
-// getLastPage returns the last page number or 0 if there is
-// an error, pages start from 1.
-func getLastPage(res *http.Response) int {
-	if len(res.Header["Link"]) < 1 {
-		return 0
+// SeedTags adds the topics of r to its tags.
+func (r *Repo) SeedTags() {
+	r.SetTags(append(r.Tags, r.Topics...)...)
+}
+
+// NamesEq reports whether the names, like tags or
+// topics, are the same and in the same order.
+func NamesEq(n1, n2 []string) bool {
+	if len(n1) != len(n2) {
+		return false
 	}
-	reSs := reMaxPage.FindStringSubmatch(res.Header["Link"][0])
-	if len(reSs) < 1 {
+	for i := range n1 {
+		if n1[i] != n2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// getLastPage returns the page number of the rel="last" link
+// or 0 if there is none, pages start from 1.
+func getLastPage(res *http.Response) int {
+	last, ok := parseLinks(res.Header)["last"]
+	if !ok {
 		return 0
 	}
-	// discard error, 0 will be treated as error.
-	page, _ := strconv.Atoi(reSs[len(reSs)-1])
-	return page
+	return pageOf(last)
 }
 
-// GetGithubRepos returns all github repositories of user.
+// GetGithubRepos returns all github repositories of user
+// with anonymous requests.
 func GetGithubRepos(user string) ([]*Repo, error) {
-	urlFormat := "https://api.github.com/users/" + user + "/starred?page=%d"
-	return getGithubRepos(urlFormat)
+	return (&GitHub{}).GetRepos(user)
+}
+
+// GetRepos returns all starred github repositories of user.
+// If some pages fail GetRepos returns the repositories of the
+// other pages and a PartialErr.
+func (g *GitHub) GetRepos(user string) ([]*Repo, error) {
+	return g.GetReposContext(context.Background(), user)
+}
+
+// GetReposContext is like GetRepos but stops fetching
+// the pages when ctx is done.
+func (g *GitHub) GetReposContext(ctx context.Context, user string) ([]*Repo, error) {
+	urlFormat := g.baseURL() + "/users/" + user + "/starred?per_page=100&page=%d"
+	return g.getRepos(ctx, urlFormat)
 }
 
-func getGithubRepos(urlFormat string) ([]*Repo, error) {
+func (g *GitHub) getRepos(ctx context.Context, urlFormat string) ([]*Repo, error) {
 	url := fmt.Sprintf(urlFormat, 1)
-	res, err := requestPage(url)
+	res, err := g.requestPage(ctx, url, starHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -84,12 +218,27 @@ func getGithubRepos(urlFormat string) ([]*Repo, error) {
 		var notFound NotFoundErr
 		return nil, notFound
 	}
+	if res.StatusCode != 200 {
+		return nil, statusErr(res)
+	}
 
 	nPages := getLastPage(res)
+	g.progress(1, nPages)
 	allRepos := make([]*Repo, 0)
-	if nPages > 1 {
-		rs := getAllPages(urlFormat, nPages)
-		allRepos = append(allRepos, rs...)
+	var pErrs PartialErr
+	next := parseLinks(res.Header)["next"]
+	switch {
+	case nPages > 1:
+		// the last page is known, request the pages concurrently.
+		allRepos, pErrs = g.getAllPages(ctx, urlFormat, nPages)
+	case next != "":
+		// only the next page is known, follow the pages.
+		var err error
+		allRepos, err = followPages(ctx, next, 2, g.getPage)
+		pErrs, _ = err.(PartialErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	bs, err := ioutil.ReadAll(res.Body)
@@ -102,69 +251,270 @@ func getGithubRepos(urlFormat string) ([]*Repo, error) {
 		return nil, err
 	}
 	allRepos = append(allRepos, repos...)
+	if len(pErrs) > 0 {
+		return allRepos, pErrs
+	}
 	return allRepos, nil
 }
 
-// requestPage request the page with github header.
-func requestPage(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// getPage returns the repositories of the page and its header.
+func (g *GitHub) getPage(ctx context.Context, pageURL string) ([]*Repo, http.Header, error) {
+	res, err := g.requestPage(ctx, pageURL, starHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, nil, statusErr(res)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	repos, err := unmarshalRepos(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repos, res.Header, nil
+}
+
+func statusErr(res *http.Response) error {
+	return fmt.Errorf("unexpected status: %s", res.Status)
+}
+
+// baseURL returns the URL of the API without
+// trailing slash.
+func (g *GitHub) baseURL() string {
+	if g.BaseURL == "" {
+		return DefaultGitHubURL
+	}
+	return strings.TrimRight(g.BaseURL, "/")
+}
+
+// progress reports done of total pages fetched, total
+// smaller than 1 means there is only one page.
+func (g *GitHub) progress(done, total int) {
+	if g.Progress == nil {
+		return
+	}
+	if total < 1 {
+		total = 1
+	}
+	g.Progress(done, total)
+}
+
+// requestPage request the page with github header and
+// the token of g, if any. Requests rejected by the rate
+// limit or failed by server errors are retried.
+func (g *GitHub) requestPage(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	wait := backoff
+	for i := 0; ; i++ {
+		if err := g.waitResume(ctx); err != nil {
+			return nil, err
+		}
+		res, err := g.doRequest(ctx, url, header)
+		lastTry := i >= maxRetries
+		if err != nil {
+			if lastTry || ctx.Err() != nil {
+				return nil, err
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+			wait *= 2
+			continue
+		}
+
+		reset, limited := rateLimitWait(res)
+		if !limited && res.StatusCode < 500 {
+			return res, nil
+		}
+		if limited && reset > maxPause {
+			res.Body.Close()
+			return nil, RateLimitErr(reset)
+		}
+		if lastTry {
+			if limited {
+				res.Body.Close()
+				return nil, RateLimitErr(reset)
+			}
+			return res, nil
+		}
+		res.Body.Close()
+
+		if limited && reset > wait {
+			err = g.pause(ctx, reset)
+		} else {
+			err = sleep(ctx, wait)
+		}
+		if err != nil {
+			return nil, err
+		}
+		wait *= 2
+	}
+}
+
+func (g *GitHub) doRequest(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "token "+g.Token)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
 	return client.Do(req)
 }
 
-func getPageBody(url string) ([]byte, error) {
-	res, err := requestPage(url)
+func (g *GitHub) getPageBody(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	res, err := g.requestPage(ctx, url, header)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, statusErr(res)
+	}
 	return ioutil.ReadAll(res.Body)
 }
 
+// githubRepo is a repository of the GitHub API.
+type githubRepo struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	Description string    `json:"description"`
+	HTMLURL     string    `json:"html_url"`
+	Language    string    `json:"language"`
+	Topics      []string  `json:"topics"`
+	Stars       int       `json:"stargazers_count"`
+	Forks       int       `json:"forks_count"`
+	OpenIssues  int       `json:"open_issues_count"`
+	Archived    bool      `json:"archived"`
+	Fork        bool      `json:"fork"`
+	CreatedAt   time.Time `json:"created_at"`
+	PushedAt    time.Time `json:"pushed_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	License     struct {
+		Key string `json:"key"`
+	} `json:"license"`
+	Owner struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"owner"`
+}
+
+func (gr *githubRepo) repo() *Repo {
+	return &Repo{
+		ID:         gr.ID,
+		Name:       gr.Name,
+		Desc:       gr.Description,
+		URLHTTP:    gr.HTMLURL,
+		Lang:       gr.Language,
+		Source:     SourceGitHub,
+		SourceID:   gr.ID,
+		Owner:      gr.Owner.Login,
+		FullName:   gr.FullName,
+		Topics:     gr.Topics,
+		Stars:      gr.Stars,
+		Forks:      gr.Forks,
+		OpenIssues: gr.OpenIssues,
+		Archived:   gr.Archived,
+		Fork:       gr.Fork,
+		License:    gr.License.Key,
+		OwnerType:  gr.Owner.Type,
+		CreatedAt:  gr.CreatedAt,
+		PushedAt:   gr.PushedAt,
+		UpdatedAt:  gr.UpdatedAt,
+	}
+}
+
+// starredRepo is a repository in the star+json media type.
+type starredRepo struct {
+	StarredAt time.Time   `json:"starred_at"`
+	Repo      *githubRepo `json:"repo"`
+}
+
+// unmarshalRepos decodes a page of GitHub repositories
+// in the default or in the star+json media type.
 func unmarshalRepos(data []byte) ([]*Repo, error) {
-	starreds := make([]*Repo, 0)
-	if err := json.Unmarshal(data, &starreds); err != nil {
+	stars := make([]*starredRepo, 0)
+	if err := json.Unmarshal(data, &stars); err != nil {
 		return nil, err
 	}
+	if len(stars) == 0 || stars[0].Repo == nil {
+		grs := make([]*githubRepo, 0)
+		if err := json.Unmarshal(data, &grs); err != nil {
+			return nil, err
+		}
+		starreds := make([]*Repo, len(grs))
+		for i, gr := range grs {
+			starreds[i] = gr.repo()
+		}
+		return starreds, nil
+	}
+
+	starreds := make([]*Repo, 0, len(stars))
+	for _, star := range stars {
+		if star.Repo == nil {
+			continue
+		}
+		r := star.Repo.repo()
+		r.StarredAt = star.StarredAt
+		starreds = append(starreds, r)
+	}
 	return starreds, nil
 }
 
-func extractRepo(repoCh chan<- []*Repo, limit chan struct{}, url string) {
+// page is the result of fetching one page.
+type page struct {
+	repos []*Repo
+	err   *PageErr
+}
+
+func (g *GitHub) extractRepo(ctx context.Context, pageCh chan<- page, limit chan struct{}, url string, n int) {
 	// release space to another go routine execute.
 	defer func() { <-limit }()
-	body, err := getPageBody(url)
+	body, err := g.getPageBody(ctx, url, starHeader)
 	if err != nil {
-		repoCh <- nil
+		pageCh <- page{err: &PageErr{Page: n, Err: err}}
 		return
 	}
 
 	repos, err := unmarshalRepos(body)
 	if err != nil {
-		repoCh <- nil
+		pageCh <- page{err: &PageErr{Page: n, Err: err}}
 		return
 	}
 
-	repoCh <- repos
+	pageCh <- page{repos: repos}
 }
 
 // getAllPages request pages [2, nPages] concurrently and extract
-// the repositories.
-func getAllPages(urlFormat string, nPages int) []*Repo {
-	repoCh := make(chan []*Repo)
+// the repositories. The pages that failed are returned in the
+// PartialErr. When ctx is done the pages left are not requested.
+func (g *GitHub) getAllPages(ctx context.Context, urlFormat string, nPages int) ([]*Repo, PartialErr) {
+	pageCh := make(chan page)
 	// limit the go routines.
 	limit := make(chan struct{}, 100)
 	done := make(chan struct{})
 
 	allRepos := make([]*Repo, 0)
+	var pErrs PartialErr
 	go func() {
 		for i := 2; i <= nPages; i++ {
-			if rs := <-repoCh; rs != nil {
-				allRepos = append(allRepos, rs...)
+			p := <-pageCh
+			if p.err != nil {
+				pErrs = append(pErrs, p.err)
+			} else {
+				allRepos = append(allRepos, p.repos...)
 			}
+			g.progress(i, nPages)
 		}
 		done <- struct{}{}
 	}()
@@ -173,10 +523,17 @@ func getAllPages(urlFormat string, nPages int) []*Repo {
 		url := fmt.Sprintf(urlFormat, i)
 		// if there are less then limit of
 		// go routines executing take your pass.
-		limit <- struct{}{}
-		go extractRepo(repoCh, limit, url)
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+			// the page is not requested, count it as failed.
+			pageCh <- page{err: &PageErr{Page: i, Err: ctx.Err()}}
+			continue
+		}
+		go g.extractRepo(ctx, pageCh, limit, url, i)
 	}
 
 	<-done
-	return allRepos
+	sort.Slice(pErrs, func(i, j int) bool { return pErrs[i].Page < pErrs[j].Page })
+	return allRepos, pErrs
 }
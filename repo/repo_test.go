@@ -1,9 +1,13 @@
 package repo
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -38,7 +42,8 @@ func TestGetGithubRepos(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			s := httptest.NewServer(tc.handlerFn)
 
-			_, err := getGithubRepos(s.URL + "/%d")
+			g := &GitHub{}
+			_, err := g.getRepos(context.Background(), s.URL+"/%d")
 			if err != tc.expectedErr {
 				t.Fatalf("failed to get repos: %q", err)
 			}
@@ -48,6 +53,123 @@ func TestGetGithubRepos(t *testing.T) {
 
 }
 
+func TestEnterprise(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/users/foo/starred" {
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Link", `<https://ghe.example.com/api/v3/user/7/starred?per_page=30&page=2>; rel="next",
+			<https://ghe.example.com/api/v3/user/7/starred?per_page=30&page=3>; rel="last"`)
+		fmt.Fprintf(w, `[{"id": %s, "name": "bar", "full_name": "foo/bar", "owner": {"login": "foo"}}]`,
+			r.URL.Query().Get("page"))
+	}))
+	defer s.Close()
+
+	g := &GitHub{BaseURL: s.URL + "/api/v3/"}
+	rs, err := g.GetReposContext(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("failed to get repos: %v", err)
+	}
+	if len(rs) != 3 {
+		t.Fatalf("expected 3 repos; got %d", len(rs))
+	}
+	if rs[0].Owner != "foo" || rs[0].FullName != "foo/bar" {
+		t.Fatalf("expected owner foo and full name foo/bar; got %+v", rs[0])
+	}
+}
+
+func TestPartialPages(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/3":
+			http.Error(w, http.StatusText(404), http.StatusNotFound)
+			return
+		case "/5":
+			w.Write([]byte("not json"))
+			return
+		}
+		w.Header().Set("Link", `<https://api.github.com/user/0/starred?page=5>; rel="last"`)
+		w.Write([]byte(`[{"id": 1, "name": "foo"}]`))
+	}))
+	defer s.Close()
+
+	g := &GitHub{}
+	rs, err := g.getRepos(context.Background(), s.URL+"/%d")
+	pErr, ok := err.(PartialErr)
+	if !ok {
+		t.Fatalf("expected partial error; got %v", err)
+	}
+	if len(pErr) != 2 || pErr[0].Page != 3 || pErr[1].Page != 5 {
+		t.Fatalf("expected pages 3 and 5 to fail; got %v", pErr)
+	}
+	if len(rs) != 3 {
+		t.Fatalf("expected 3 repos; got %d", len(rs))
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("Link", `<https://api.github.com/user/0/starred?page=500>; rel="last"`)
+		w.Write([]byte(`[{"id": 1, "name": "foo"}]`))
+	}))
+	defer s.Close()
+
+	// the client disconnects after the first page.
+	g := &GitHub{Progress: func(done, total int) {
+		if done == 1 {
+			cancel()
+		}
+	}}
+	_, err := g.getRepos(ctx, s.URL+"/%d")
+	if err != context.Canceled {
+		t.Fatalf("expected %v; got %v", context.Canceled, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 1 {
+		t.Fatalf("expected 1 request after cancel; got %d", requests)
+	}
+}
+
+func TestToken(t *testing.T) {
+	const token = "secret-token"
+	var got []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.Write([]byte("[]"))
+	}))
+	defer s.Close()
+
+	g := &GitHub{Token: token}
+	if _, err := g.getRepos(context.Background(), s.URL+"/%d"); err != nil {
+		t.Fatalf("failed to get repos: %v", err)
+	}
+	if len(got) != 1 || got[0] != "token "+token {
+		t.Fatalf("expected authorization header %q; got %q", "token "+token, got)
+	}
+	if strings.Contains(g.String(), token) {
+		t.Fatalf("token leaked by String: %s", g)
+	}
+
+	got = nil
+	g = &GitHub{}
+	if _, err := g.getRepos(context.Background(), s.URL+"/%d"); err != nil {
+		t.Fatalf("failed to get repos: %v", err)
+	}
+	if len(got) != 1 || got[0] != "" {
+		t.Fatalf("anonymous request sent authorization header %q", got)
+	}
+}
+
 func TestUnmarshalRepos(t *testing.T) {
 	bs, err := ioutil.ReadFile("mock.json")
 	if err != nil {
@@ -66,6 +188,18 @@ func TestUnmarshalRepos(t *testing.T) {
 	}
 }
 
+func TestSeedTags(t *testing.T) {
+	r := &Repo{Tags: []string{"mine", "go"}, Topics: []string{"go", "cli"}}
+	r.SeedTags()
+	expected := []string{"mine", "go", "cli"}
+	if fmt.Sprint(r.Tags) != fmt.Sprint(expected) {
+		t.Fatalf("expected tags %v; got %v", expected, r.Tags)
+	}
+	if len(r.Topics) != 2 {
+		t.Fatalf("topics should not change; got %v", r.Topics)
+	}
+}
+
 func TestSetTags(t *testing.T) {
 	tt := []struct {
 		name         string
@@ -0,0 +1,270 @@
+package repo
+
+import (
+	// embed the default rules.
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rules suggest tags to the repositories that match their
+// conditions. They are read from JSON like:
+//
+//	{"rules": [
+//		{"tag": "popular", "when": {"stars": {"min": 1001, "max": 10000}}},
+//		{"tag": "{license}"}
+//	], "activity": {"active_days": 90}}
+type Rules struct {
+	Rules []*Rule `json:"rules"`
+	// Activity are the thresholds of the activity tags,
+	// the missing ones are the default.
+	Activity *Activity `json:"activity,omitempty"`
+}
+
+// Rule suggests Tag to the repositories that match all the
+// conditions of When. Tag can have the fields {owner_type},
+// {license} and {language} of the repository, the rule does
+// not match if any of them is empty.
+type Rule struct {
+	// Name identifies the rule, the default is Tag.
+	Name string `json:"name,omitempty"`
+	Tag  string `json:"tag"`
+	// Score is the confidence of the rule, from 0 to 1.
+	// The default is 1.
+	Score float64   `json:"score,omitempty"`
+	When  Condition `json:"when"`
+}
+
+// Condition are the conditions of a rule, the empty ones
+// match all the repositories.
+type Condition struct {
+	Stars *Range `json:"stars,omitempty"`
+	// Language is any of the languages, in any case.
+	Language []string `json:"language,omitempty"`
+	// LicenseFamily is any of permissive, copyleft,
+	// weak-copyleft or public-domain.
+	LicenseFamily []string `json:"license_family,omitempty"`
+	// Description is a regular expression.
+	Description string `json:"description,omitempty"`
+	// Topic is any of the topics.
+	Topic    []string `json:"topic,omitempty"`
+	Archived *bool    `json:"archived,omitempty"`
+	// PushAgeDays is the range of days since the last push.
+	PushAgeDays *Range `json:"push_age_days,omitempty"`
+
+	desc *regexp.Regexp
+}
+
+// Range is an inclusive range, a nil bound is open.
+type Range struct {
+	Min *int `json:"min,omitempty"`
+	Max *int `json:"max,omitempty"`
+}
+
+func (rg *Range) contains(n int) bool {
+	return (rg.Min == nil || n >= *rg.Min) && (rg.Max == nil || n <= *rg.Max)
+}
+
+func (rg *Range) String() string {
+	s := ".."
+	if rg.Min != nil {
+		s = strconv.Itoa(*rg.Min) + s
+	}
+	if rg.Max != nil {
+		s += strconv.Itoa(*rg.Max)
+	}
+	return s
+}
+
+// licenseFamilies are the families of the license keys.
+var licenseFamilies = map[string]string{
+	"mit":          "permissive",
+	"apache-2.0":   "permissive",
+	"bsd-2-clause": "permissive",
+	"bsd-3-clause": "permissive",
+	"isc":          "permissive",
+	"0bsd":         "permissive",
+	"zlib":         "permissive",
+	"gpl-2.0":      "copyleft",
+	"gpl-3.0":      "copyleft",
+	"agpl-3.0":     "copyleft",
+	"lgpl-2.1":     "weak-copyleft",
+	"lgpl-3.0":     "weak-copyleft",
+	"mpl-2.0":      "weak-copyleft",
+	"epl-2.0":      "weak-copyleft",
+	"unlicense":    "public-domain",
+	"cc0-1.0":      "public-domain",
+}
+
+// ParseRules parses the JSON rules of data.
+func ParseRules(data []byte) (*Rules, error) {
+	rs := &Rules{}
+	if err := json.Unmarshal(data, rs); err != nil {
+		return nil, err
+	}
+	if rs.Activity == nil {
+		rs.Activity = DefaultActivity()
+	}
+	if err := rs.Activity.setDefaults(); err != nil {
+		return nil, err
+	}
+	for i, r := range rs.Rules {
+		if r.Tag == "" {
+			return nil, fmt.Errorf("rule %d has no tag", i+1)
+		}
+		if r.Name == "" {
+			r.Name = r.Tag
+		}
+		if r.Score == 0 {
+			r.Score = 1
+		}
+		if r.Score < 0 || r.Score > 1 {
+			return nil, fmt.Errorf("rule %s: score %v out of 0 to 1", r.Name, r.Score)
+		}
+		if r.When.Description != "" {
+			re, err := regexp.Compile(r.When.Description)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+			}
+			r.When.desc = re
+		}
+	}
+	return rs, nil
+}
+
+// defaultRules are the rules used without a rules file.
+//
+//go:embed rules.json
+var defaultRules []byte
+
+// DefaultRules returns the default rules, they suggest the
+// popularity, the owner type and the license.
+func DefaultRules() *Rules {
+	rs, err := ParseRules(defaultRules)
+	if err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// Suggest returns the tags of the rules that match r.
+func (rs *Rules) Suggest(r *Repo) []Suggestion {
+	return rs.suggestAt(r, time.Now())
+}
+
+func (rs *Rules) suggestAt(r *Repo, now time.Time) []Suggestion {
+	suggestions := make([]Suggestion, 0)
+	for _, rule := range rs.Rules {
+		tag, fields, ok := rule.tag(r)
+		if !ok {
+			continue
+		}
+		reasons, ok := rule.When.match(r, now)
+		if !ok {
+			continue
+		}
+		reason := "matches all repositories"
+		if reasons = append(fields, reasons...); len(reasons) > 0 {
+			reason = strings.Join(reasons, ", ")
+		}
+		suggestions = append(suggestions, Suggestion{Tag: tag, Score: rule.Score,
+			Rule: "rules/" + rule.Name, Reason: reason})
+	}
+	return suggestions
+}
+
+// tag expands the fields of the rule tag, it also
+// returns the fields used.
+func (rule *Rule) tag(r *Repo) (string, []string, bool) {
+	fields := []struct{ name, value string }{
+		{"{owner_type}", r.OwnerType},
+		{"{license}", r.License},
+		{"{language}", r.Lang},
+	}
+	tag := rule.Tag
+	used := make([]string, 0)
+	for _, f := range fields {
+		if !strings.Contains(tag, f.name) {
+			continue
+		}
+		if f.value == "" {
+			return "", nil, false
+		}
+		tag = strings.Replace(tag, f.name, f.value, -1)
+		used = append(used, strings.Trim(f.name, "{}")+" "+f.value)
+	}
+	return tag, used, true
+}
+
+// match reports whether r matches c and why.
+func (c *Condition) match(r *Repo, now time.Time) ([]string, bool) {
+	reasons := make([]string, 0)
+	if c.Stars != nil {
+		if !c.Stars.contains(r.Stars) {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("%d stars in %s", r.Stars, c.Stars))
+	}
+	if len(c.Language) > 0 {
+		if !anyOf(c.Language, r.Lang, strings.EqualFold) {
+			return nil, false
+		}
+		reasons = append(reasons, "language "+r.Lang)
+	}
+	if len(c.LicenseFamily) > 0 {
+		family := licenseFamilies[strings.ToLower(r.License)]
+		if !anyOf(c.LicenseFamily, family, strings.EqualFold) {
+			return nil, false
+		}
+		reasons = append(reasons, "license "+r.License+" is "+family)
+	}
+	if c.desc != nil {
+		if !c.desc.MatchString(r.Desc) {
+			return nil, false
+		}
+		reasons = append(reasons, "description matches "+c.Description)
+	}
+	if len(c.Topic) > 0 {
+		found := ""
+		for _, topic := range r.Topics {
+			if anyOf(c.Topic, topic, strings.EqualFold) {
+				found = topic
+				break
+			}
+		}
+		if found == "" {
+			return nil, false
+		}
+		reasons = append(reasons, "topic "+found)
+	}
+	if c.Archived != nil {
+		if r.Archived != *c.Archived {
+			return nil, false
+		}
+		reasons = append(reasons, "archived is "+strconv.FormatBool(r.Archived))
+	}
+	if c.PushAgeDays != nil {
+		if r.PushedAt.IsZero() {
+			return nil, false
+		}
+		days := int(now.Sub(r.PushedAt).Hours() / 24)
+		if !c.PushAgeDays.contains(days) {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("pushed %d days ago", days))
+	}
+	return reasons, true
+}
+
+func anyOf(values []string, v string, eq func(a, b string) bool) bool {
+	for _, value := range values {
+		if eq(value, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	rs, err := ParseRules([]byte(`{"rules": [
+		{"tag": "go-cli", "when": {"language": ["go"], "topic": ["cli", "terminal"]}},
+		{"tag": "copyleft", "when": {"license_family": ["copyleft"]}},
+		{"tag": "parser", "when": {"description": "(?i)\\bpars(er|ing)\\b"}},
+		{"tag": "abandoned", "when": {"archived": false, "push_age_days": {"min": 365}}},
+		{"tag": "lang-{language}", "when": {"stars": {"min": 10}}}
+	]}`))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	r := &Repo{Lang: "Go", Topics: []string{"terminal"}, License: "gpl-3.0",
+		Desc: "A fast Parser", Stars: 10, PushedAt: now.AddDate(-2, 0, 0)}
+	got := rs.suggestAt(r, now)
+	want := []string{"go-cli", "copyleft", "parser", "abandoned", "lang-Go"}
+	if !reflect.DeepEqual(Tags(got), want) {
+		t.Fatalf("expected %v; got %v", want, Tags(got))
+	}
+	if got[4].Rule != "rules/lang-{language}" || got[4].Score != 1 ||
+		got[4].Reason != "language Go, 10 stars in 10.." {
+		t.Errorf("got wrong suggestion %+v", got[4])
+	}
+
+	r = &Repo{Lang: "Rust", Topics: []string{"cli"}, License: "mit", Archived: true,
+		PushedAt: now.AddDate(-2, 0, 0)}
+	if got := rs.suggestAt(r, now); len(got) != 0 {
+		t.Fatalf("expected no suggestions; got %v", got)
+	}
+
+	bad := []string{
+		`{"rules": [{"when": {"stars": {"min": 1}}}]}`,
+		`{"rules": [{"tag": "x", "when": {"description": "("}}]}`,
+		`{"rules": {}}`,
+		`{"rules": [], "activity": {"active_days": 800}}`,
+	}
+	for _, b := range bad {
+		if _, err := ParseRules([]byte(b)); err == nil {
+			t.Errorf("expected error parsing %s", b)
+		}
+	}
+}
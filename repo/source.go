@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// SourceGitHub is the name of the GitHub source.
+const SourceGitHub = "github"
+
+// Source is a forge where users star repositories.
+type Source interface {
+	// Name identifies the source of the repositories.
+	Name() string
+	// Starred returns all the repositories starred by user.
+	Starred(ctx context.Context, user string) ([]*Repo, error)
+	// Details fetches the current metadata of r.
+	Details(ctx context.Context, r *Repo) (*Repo, error)
+}
+
+// Syncer is a Source that can fetch only the
+// repositories starred after the last sync.
+type Syncer interface {
+	Source
+	SyncContext(ctx context.Context, user string, state *SyncState) ([]*Repo, *SyncState, error)
+}
+
+var _ Syncer = (*GitHub)(nil)
+
+// Name returns SourceGitHub.
+func (g *GitHub) Name() string { return SourceGitHub }
+
+// Starred is the same as GetReposContext.
+func (g *GitHub) Starred(ctx context.Context, user string) ([]*Repo, error) {
+	return g.GetReposContext(ctx, user)
+}
+
+// Details fetches the repository r from GitHub.
+func (g *GitHub) Details(ctx context.Context, r *Repo) (*Repo, error) {
+	data, err := g.getPageBody(ctx, g.baseURL()+"/repos/"+fullName(r), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	gr := &githubRepo{}
+	if err := json.Unmarshal(data, gr); err != nil {
+		return nil, err
+	}
+	details := gr.repo()
+	// keep the catalog id and the user's data.
+	details.ID, details.Tags = r.ID, r.Tags
+	details.StarredAt, details.Status = r.StarredAt, r.Status
+	return details, nil
+}
+
+// fullName returns the owner/name of the repository.
+func fullName(r *Repo) string {
+	if r.FullName != "" {
+		return r.FullName
+	}
+	return r.Owner + "/" + r.Name
+}
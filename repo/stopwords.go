@@ -0,0 +1,20 @@
+package repo
+
+// stopwords are the words ignored in the descriptions, the
+// common English words and the ones of any description.
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true,
+	"this": true, "from": true, "your": true, "are": true, "can": true,
+	"you": true, "not": true, "all": true, "any": true, "has": true,
+	"have": true, "its": true, "into": true, "more": true, "one": true,
+	"our": true, "out": true, "over": true, "than": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true,
+	"use": true, "used": true, "using": true, "via": true, "was": true,
+	"what": true, "when": true, "which": true, "who": true, "will": true,
+	"without": true, "also": true, "about": true, "other": true, "own": true,
+	"but": true, "how": true, "written": true, "based": true, "simple": true,
+	"easy": true, "fast": true, "small": true, "lightweight": true, "minimal": true,
+	"awesome": true, "powerful": true, "modern": true, "new": true, "best": true,
+	"project": true, "repository": true, "repo": true, "code": true,
+	"implementation": true, "library": true, "tool": true, "way": true,
+}
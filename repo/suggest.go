@@ -1,51 +1,121 @@
 package repo
 
 import (
+	"context"
 	"encoding/json"
+	"sort"
 )
 
-type repoSuggest struct {
-	Stars   int `json:"stargazers_count"`
-	License struct {
-		Key string `jsont:"key"`
-	} `json:"license"`
-	Owner struct {
-		Type string `json:"type"`
-	} `json:"owner"`
+// Suggest suggests tags to repository with anonymous requests.
+func Suggest(repoName string) ([]string, error) {
+	return (&GitHub{}).Suggest(repoName)
+}
+
+// Suggestion is a suggested tag and why it was suggested.
+type Suggestion struct {
+	Tag string `json:"tag"`
+	// Score is the confidence of the suggestion, from 0 to 1.
+	Score float64 `json:"score"`
+	// Rule is what suggested the tag, like rules/popular,
+	// readme, manifest/go.mod or learner.
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+	// Applied reports whether the repository has the tag.
+	Applied bool `json:"applied,omitempty"`
+}
+
+// RankOptions are the options of Rank.
+type RankOptions struct {
+	// MinScore is the minimum score of the suggestions.
+	MinScore float64
+	// Limit is the maximum number of suggestions,
+	// 0 has no limit.
+	Limit int
+	// KeepApplied keeps the tags the repository has,
+	// marked as applied.
+	KeepApplied bool
+	// Rejected are the tags the user rejected,
+	// they are never suggested.
+	Rejected []string
+}
+
+// Rank merges the suggestions to r, keeping the highest score
+// of each tag, and sorts them by score. The tags and system
+// tags r has are removed unless opts.KeepApplied.
+func Rank(r *Repo, suggestions []Suggestion, opts RankOptions) []Suggestion {
+	has := make(map[string]bool, len(r.Tags)+len(r.SystemTags))
+	for _, tag := range r.Tags {
+		has[tag] = true
+	}
+	for _, tag := range r.SystemTags {
+		has[tag] = true
+	}
+	rejected := make(map[string]bool, len(opts.Rejected))
+	for _, tag := range opts.Rejected {
+		rejected[tag] = true
+	}
+
+	best := make(map[string]int)
+	ranked := make([]Suggestion, 0, len(suggestions))
+	for _, s := range suggestions {
+		if s.Score < opts.MinScore || has[s.Tag] && !opts.KeepApplied || rejected[s.Tag] {
+			continue
+		}
+		s.Applied = has[s.Tag]
+		if i, ok := best[s.Tag]; ok {
+			if s.Score > ranked[i].Score {
+				ranked[i] = s
+			}
+			continue
+		}
+		best[s.Tag] = len(ranked)
+		ranked = append(ranked, s)
+	}
+
+	// the order of the equal scores is kept.
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	if opts.Limit > 0 && len(ranked) > opts.Limit {
+		ranked = ranked[:opts.Limit]
+	}
+	return ranked
 }
 
 // Suggest suggests tags to repository.
-func Suggest(repoName string) ([]string, error) {
-	data, err := getPageBody("https://api.github.com/repos/" + repoName)
+func (g *GitHub) Suggest(repoName string) ([]string, error) {
+	return g.SuggestContext(context.Background(), repoName)
+}
+
+// SuggestContext is like Suggest but the request
+// is canceled when ctx is done.
+func (g *GitHub) SuggestContext(ctx context.Context, repoName string) ([]string, error) {
+	data, err := g.getPageBody(ctx, g.baseURL()+"/repos/"+repoName, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	rSug := &repoSuggest{}
-	err = json.Unmarshal(data, rSug)
+	gr := &githubRepo{}
+	err = json.Unmarshal(data, gr)
 	if err != nil {
 		return nil, err
 	}
 
-	return suggest(rSug), nil
+	return suggest(gr.repo()), nil
 }
 
-func suggest(rSug *repoSuggest) []string {
-	suggestions := make([]string, 1)
-	if rSug.Stars > 10000 {
-		suggestions[0] = "very-popular"
-	} else if rSug.Stars > 1000 {
-		suggestions[0] = "popular"
-	} else {
-		suggestions[0] = "not-popular"
-	}
+// builtinRules are the rules of suggest.
+var builtinRules = DefaultRules()
 
-	if rSug.Owner.Type != "" {
-		suggestions = append(suggestions, rSug.Owner.Type+"-owner")
-	}
-	if rSug.License.Key != "" {
-		suggestions = append(suggestions, rSug.License.Key)
-	}
+func suggest(r *Repo) []string {
+	return Tags(builtinRules.Suggest(r))
+}
 
-	return suggestions
+// Tags returns the tags of suggestions.
+func Tags(suggestions []Suggestion) []string {
+	tags := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		tags[i] = s.Tag
+	}
+	return tags
 }
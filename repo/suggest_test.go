@@ -3,15 +3,12 @@ package repo
 import "testing"
 
 func TestSuggest(t *testing.T) {
-	r1 := &repoSuggest{Stars: 1000}
-	r1.License.Key = "mit"
-	r1.Owner.Type = "User"
-	r2 := &repoSuggest{Stars: 90000}
-	r3 := &repoSuggest{Stars: 5000}
-	r3.Owner.Type = "Company"
+	r1 := &Repo{Stars: 1000, License: "mit", OwnerType: "User"}
+	r2 := &Repo{Stars: 90000}
+	r3 := &Repo{Stars: 5000, OwnerType: "Company"}
 	tt := []struct {
 		name     string
-		rSug     *repoSuggest
+		rSug     *Repo
 		expected []string
 	}{
 		{"notPop", r1, []string{"not-popular", "User-owner", "mit"}},
@@ -36,3 +33,49 @@ func TestSuggest(t *testing.T) {
 		})
 	}
 }
+
+func TestRank(t *testing.T) {
+	r := &Repo{Tags: []string{"go"}, SystemTags: []string{TagActive}}
+	suggestions := []Suggestion{
+		{Tag: "cli", Score: 0.6, Rule: "readme"},
+		{Tag: "go", Score: 1, Rule: "rules/go"},
+		{Tag: "web", Score: 0.3, Rule: "learner"},
+		{Tag: "cli", Score: 0.9, Rule: "manifest/go.mod"},
+		{Tag: "mit", Score: 0.9, Rule: "rules/license"},
+		{Tag: TagActive, Score: 1, Rule: ActivityRule},
+	}
+	tt := []struct {
+		name string
+		opts RankOptions
+		tags []string
+	}{
+		{"all", RankOptions{}, []string{"cli", "mit", "web"}},
+		{"minScore", RankOptions{MinScore: 0.5}, []string{"cli", "mit"}},
+		{"limit", RankOptions{Limit: 1}, []string{"cli"}},
+		{"applied", RankOptions{KeepApplied: true, Limit: 3}, []string{"go", TagActive, "cli"}},
+		{"rejected", RankOptions{Rejected: []string{"cli", "go"}, KeepApplied: true},
+			[]string{TagActive, "mit", "web"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Rank(r, suggestions, tc.opts)
+			tags := Tags(got)
+			if len(tags) != len(tc.tags) {
+				t.Fatalf("expected %v; got %v", tc.tags, tags)
+			}
+			for i := range tags {
+				if tags[i] != tc.tags[i] {
+					t.Fatalf("expected %v; got %v", tc.tags, tags)
+				}
+			}
+			for _, s := range got {
+				if s.Tag == "cli" && s.Rule != "manifest/go.mod" {
+					t.Errorf("expected the best cli suggestion; got %+v", s)
+				}
+				if s.Applied != (s.Tag == "go" || s.Tag == TagActive) {
+					t.Errorf("wrong applied mark %+v", s)
+				}
+			}
+		})
+	}
+}
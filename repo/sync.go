@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// starHeader requests the star+json media type, that
+// includes when each repository was starred.
+var starHeader = http.Header{"Accept": {"application/vnd.github.v3.star+json"}}
+
+// SyncState is the state of the last sync of
+// the starred repositories of a user.
+type SyncState struct {
+	User string
+	// LastStarredAt is the newest starred_at seen.
+	LastStarredAt time.Time
+	// ETag is the entity tag of the first page.
+	ETag string
+}
+
+// Update sets the LastStarredAt of s to the newest
+// starred_at of rs.
+func (s *SyncState) Update(rs []*Repo) {
+	for _, r := range rs {
+		if r.StarredAt.After(s.LastStarredAt) {
+			s.LastStarredAt = r.StarredAt
+		}
+	}
+}
+
+// SyncContext returns the repositories starred by user after
+// the last sync in state and the new state. Pages are followed
+// by their rel="next" link from the newest star until a star of
+// the last sync is found.
+// If the first page did not change since the last sync no
+// repositories are returned and the request costs no quota.
+func (g *GitHub) SyncContext(ctx context.Context, user string, state *SyncState) ([]*Repo, *SyncState, error) {
+	urlFormat := g.baseURL() + "/users/" + user +
+		"/starred?sort=created&direction=desc&per_page=100&page=%d"
+	return g.sync(ctx, urlFormat, state)
+}
+
+func (g *GitHub) sync(ctx context.Context, urlFormat string, state *SyncState) ([]*Repo, *SyncState, error) {
+	newState := &SyncState{User: state.User, LastStarredAt: state.LastStarredAt}
+	newRepos := make([]*Repo, 0)
+
+	next, nPages := fmt.Sprintf(urlFormat, 1), 0
+	for page := 1; next != ""; page++ {
+		header := http.Header{}
+		for k, v := range starHeader {
+			header[k] = v
+		}
+		if page == 1 && state.ETag != "" {
+			header.Set("If-None-Match", state.ETag)
+		}
+
+		res, err := g.requestPage(ctx, next, header)
+		if err != nil {
+			return nil, nil, err
+		}
+		bs, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case res.StatusCode == http.StatusNotModified:
+			return newRepos, state, nil
+		case res.StatusCode == 404:
+			var notFound NotFoundErr
+			return nil, nil, notFound
+		case res.StatusCode != 200:
+			return nil, nil, &PageErr{Page: page, Err: statusErr(res)}
+		}
+
+		if page == 1 {
+			newState.ETag = res.Header.Get("ETag")
+			nPages = getLastPage(res)
+		}
+		g.progress(page, nPages)
+		next = parseLinks(res.Header)["next"]
+
+		repos, err := unmarshalRepos(bs)
+		if err != nil {
+			return nil, nil, &PageErr{Page: page, Err: err}
+		}
+		for _, r := range repos {
+			if !r.StarredAt.After(state.LastStarredAt) {
+				// reached the stars of the last sync.
+				newState.Update(newRepos)
+				return newRepos, newState, nil
+			}
+			newRepos = append(newRepos, r)
+		}
+	}
+
+	newState.Update(newRepos)
+	return newRepos, newState, nil
+}
@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// starServer serves 2 pages of 2 stars, the newest first.
+type starServer struct {
+	url      string
+	requests []string
+}
+
+var starTimes = []time.Time{
+	time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func (ss *starServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ss.requests = append(ss.requests, r.URL.Path)
+	if !strings.Contains(r.Header.Get("Accept"), "star+json") {
+		http.Error(w, "expected star+json", http.StatusBadRequest)
+		return
+	}
+
+	first := 0
+	if r.URL.Path == "/2" {
+		first = 2
+	} else {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Link", `<`+ss.url+`/2>; rel="next", <`+ss.url+`/2>; rel="last"`)
+	}
+
+	items := make([]string, 0, 2)
+	for i := first; i < first+2; i++ {
+		items = append(items, fmt.Sprintf(`{"starred_at": %q, "repo": {"id": %d}}`,
+			starTimes[i].Format(time.RFC3339), i))
+	}
+	fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+}
+
+func TestSync(t *testing.T) {
+	tt := []struct {
+		name      string
+		state     *SyncState
+		repos     int
+		requests  int
+		lastStar  time.Time
+		etag      string
+		unchanged bool
+	}{
+		{"first sync", &SyncState{}, 4, 2, starTimes[0], `"v1"`, false},
+		{"not modified", &SyncState{ETag: `"v1"`, LastStarredAt: starTimes[0]}, 0, 1, starTimes[0], `"v1"`, true},
+		{"new stars", &SyncState{ETag: `"v0"`, LastStarredAt: starTimes[1]}, 1, 1, starTimes[0], `"v1"`, false},
+		{"older page", &SyncState{LastStarredAt: starTimes[3]}, 3, 2, starTimes[0], `"v1"`, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ss := &starServer{}
+			s := httptest.NewServer(ss)
+			defer s.Close()
+			ss.url = s.URL
+
+			g := &GitHub{}
+			rs, state, err := g.sync(context.Background(), s.URL+"/%d", tc.state)
+			if err != nil {
+				t.Fatalf("failed to sync: %v", err)
+			}
+			if len(rs) != tc.repos {
+				t.Errorf("expected %d repos; got %d", tc.repos, len(rs))
+			}
+			if len(ss.requests) != tc.requests {
+				t.Errorf("expected %d requests; got %v", tc.requests, ss.requests)
+			}
+			if !state.LastStarredAt.Equal(tc.lastStar) || state.ETag != tc.etag {
+				t.Errorf("expected state %v %s; got %v %s", tc.lastStar, tc.etag,
+					state.LastStarredAt, state.ETag)
+			}
+			for _, r := range rs {
+				if r.StarredAt.IsZero() {
+					t.Errorf("repo %d has no starred_at", r.ID)
+				}
+			}
+		})
+	}
+}
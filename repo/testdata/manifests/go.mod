@@ -0,0 +1,14 @@
+module github.com/example/tool
+
+go 1.16
+
+require github.com/spf13/cobra v1.1.3
+
+require (
+	github.com/gin-gonic/gin v1.7.2
+	github.com/urfave/cli/v2 v2.3.0 // indirect
+)
+
+require  (
+	k8s.io/client-go v0.21.0
+)
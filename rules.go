@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rschio/repoTagger/repo"
+)
+
+// rulesFile has the suggestion rules of a file,
+// they are reloaded when the file changes.
+type rulesFile struct {
+	path string
+
+	mu      sync.RWMutex
+	rules   *repo.Rules
+	modTime time.Time
+}
+
+// loadRules loads the rules of the file path, or
+// the default rules if path is empty.
+func loadRules(path string) (*rulesFile, error) {
+	f := &rulesFile{path: path, rules: repo.DefaultRules()}
+	if path == "" {
+		return f, nil
+	}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Rules returns the current rules.
+func (f *rulesFile) Rules() *repo.Rules {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.rules
+}
+
+// reload parses the file again if it was modified.
+func (f *rulesFile) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	f.mu.RLock()
+	modTime := f.modTime
+	f.mu.RUnlock()
+	if info.ModTime().Equal(modTime) {
+		return nil
+	}
+
+	// an invalid file is also recorded, so
+	// each change of it fails only once.
+	f.mu.Lock()
+	f.modTime = info.ModTime()
+	f.mu.Unlock()
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	rules, err := repo.ParseRules(data)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.rules = rules
+	f.mu.Unlock()
+	return nil
+}
+
+// watch reloads the file every interval, invalid
+// rules are logged and the old ones are kept.
+func (f *rulesFile) watch(interval time.Duration) {
+	if f.path == "" {
+		return
+	}
+	for range time.Tick(interval) {
+		if err := f.reload(); err != nil {
+			log.Printf("failed to reload rules %s: %v", f.path, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package storage
+
+import "time"
+
+// Feedback is the decision of the user on a tag suggested
+// to a repository.
+type Feedback struct {
+	RepoID int    `json:"repo_id"`
+	Tag    string `json:"tag"`
+	// Rule is what suggested the tag, like the Rule of
+	// repo.Suggestion, empty if it is unknown.
+	Rule      string    `json:"rule"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Precision is how many suggestions of a rule
+// were accepted and rejected.
+type Precision struct {
+	Rule     string `json:"rule"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	// Precision is the fraction of the suggestions accepted.
+	Precision float64 `json:"precision"`
+}
+
+// NewPrecision returns the precision of rule, a rule
+// without feedback has precision 0.
+func NewPrecision(rule string, accepted, rejected int) *Precision {
+	p := &Precision{Rule: rule, Accepted: accepted, Rejected: rejected}
+	if total := accepted + rejected; total > 0 {
+		p.Precision = float64(accepted) / float64(total)
+	}
+	return p
+}
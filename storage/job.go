@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/rschio/repoTagger/repo"
+)
+
+// Status of the jobs, the failed jobs were
+// interrupted by a restart before they were done.
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is a batch suggestion over many repositories.
+type Job struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	// DryRun jobs only report the suggestions.
+	DryRun bool `json:"dry_run"`
+	// Threshold is the minimum score of the
+	// suggestions applied to the repositories.
+	Threshold float64 `json:"threshold"`
+	// Total is the number of repositories of the job
+	// and Done how many were processed.
+	Total int `json:"total"`
+	Done  int `json:"done"`
+	// Applied is the number of tags applied.
+	Applied int          `json:"applied"`
+	Report  []*JobResult `json:"report"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobResult is the result of a job to one repository.
+type JobResult struct {
+	RepoID      int               `json:"repo_id"`
+	FullName    string            `json:"full_name"`
+	Suggestions []repo.Suggestion `json:"suggestions"`
+	// Applied are the tags applied to the repository.
+	Applied []string `json:"applied,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"log"
+	"time"
+)
+
+func (s *service) SetReleasedAt(repoID int, at time.Time) error {
+	_, err := s.DB.Exec("UPDATE repo SET released_at = ? WHERE id = ?;", at, repoID)
+	if err != nil {
+		log.Printf("failed to set release of repo %d: %v", repoID, err)
+	}
+	return err
+}
+
+func (s *service) SetSystemTags(repoID int, tags []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM system_tag WHERE repo_id = ?;", repoID); err != nil {
+		return err
+	}
+	stmt := "INSERT INTO system_tag (name, repo_id) VALUES (?, ?);"
+	for _, tag := range tags {
+		if _, err := tx.Exec(stmt, tag, repoID); err != nil {
+			log.Printf("failed to insert system tag %s: %v", tag, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rschio/repoTagger/repo"
+)
+
+func TestSystemTags(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	r := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com", Tags: []string{"mine"}}
+	if err := db.InsertRepo(r); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	releasedAt := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := db.SetReleasedAt(1, releasedAt); err != nil {
+		t.Fatalf("failed to set release: %v", err)
+	}
+	if err := db.SetSystemTags(1, []string{repo.TagStale}); err != nil {
+		t.Fatalf("failed to set system tags: %v", err)
+	}
+	if err := db.SetSystemTags(1, []string{repo.TagAbandoned}); err != nil {
+		t.Fatalf("failed to set system tags: %v", err)
+	}
+
+	// the release is not changed by the source metadata.
+	r.Desc = "new"
+	if _, err := db.UpsertRepo(r); err != nil {
+		t.Fatalf("failed to update repo: %v", err)
+	}
+
+	got, err := db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if !reflect.DeepEqual(got.SystemTags, []string{repo.TagAbandoned}) ||
+		!reflect.DeepEqual(got.Tags, []string{"mine"}) || !got.ReleasedAt.Equal(releasedAt) {
+		t.Fatalf("got wrong repo: %+v", got)
+	}
+
+	rs, err := db.GetReposByTag("aband")
+	if err != nil {
+		t.Fatalf("failed to get repos by tag: %v", err)
+	}
+	if len(rs) != 1 {
+		t.Fatalf("expected repo by system tag; got %d", len(rs))
+	}
+}
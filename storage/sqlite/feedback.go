@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"log"
+	"time"
+
+	"github.com/rschio/repoTagger/storage"
+)
+
+// SetFeedback keeps the feedback of deleted repos,
+// it still counts in the precision of the rules.
+func (s *service) SetFeedback(f *storage.Feedback) error {
+	f.CreatedAt = time.Now().UTC()
+	stmt := `INSERT OR REPLACE INTO feedback (repo_id, tag, rule, accepted, created_at)
+		VALUES (?, ?, ?, ?, ?);`
+	_, err := s.DB.Exec(stmt, f.RepoID, f.Tag, f.Rule, f.Accepted, f.CreatedAt)
+	if err != nil {
+		log.Printf("failed to set feedback of repo %d: %v", f.RepoID, err)
+	}
+	return err
+}
+
+func (s *service) GetFeedback(repoID int) ([]*storage.Feedback, error) {
+	stmt := `SELECT repo_id, tag, rule, accepted, created_at FROM feedback
+		WHERE repo_id = ? ORDER BY tag;`
+	rows, err := s.DB.Query(stmt, repoID)
+	if err != nil {
+		log.Printf("failed to get feedback of repo %d: %v", repoID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	feedback := make([]*storage.Feedback, 0)
+	for rows.Next() {
+		f := &storage.Feedback{}
+		err := rows.Scan(&f.RepoID, &f.Tag, &f.Rule, &f.Accepted, &f.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		feedback = append(feedback, f)
+	}
+	return feedback, rows.Err()
+}
+
+// GetPrecision ignores the feedback without rule.
+func (s *service) GetPrecision() ([]*storage.Precision, error) {
+	stmt := `SELECT rule, SUM(accepted), SUM(NOT accepted) FROM feedback
+		WHERE rule != '' GROUP BY rule ORDER BY rule;`
+	rows, err := s.DB.Query(stmt)
+	if err != nil {
+		log.Printf("failed to get precision: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ps := make([]*storage.Precision, 0)
+	for rows.Next() {
+		var rule string
+		var accepted, rejected int
+		if err := rows.Scan(&rule, &accepted, &rejected); err != nil {
+			return nil, err
+		}
+		ps = append(ps, storage.NewPrecision(rule, accepted, rejected))
+	}
+	return ps, rows.Err()
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rschio/repoTagger/storage"
+)
+
+func TestFeedback(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	feedback := []*storage.Feedback{
+		{RepoID: 1, Tag: "cli", Rule: "readme", Accepted: false},
+		{RepoID: 1, Tag: "mit", Rule: "rules/license", Accepted: true},
+		{RepoID: 2, Tag: "cli", Rule: "readme", Accepted: true},
+		{RepoID: 2, Tag: "web", Rule: "readme", Accepted: true},
+		{RepoID: 2, Tag: "mine", Accepted: true},
+		// replaces the earlier feedback.
+		{RepoID: 1, Tag: "cli", Rule: "readme", Accepted: true},
+		{RepoID: 1, Tag: "cli", Rule: "readme", Accepted: false},
+	}
+	for _, fb := range feedback {
+		if err := db.SetFeedback(fb); err != nil {
+			t.Fatalf("failed to set feedback: %v", err)
+		}
+	}
+
+	got, err := db.GetFeedback(1)
+	if err != nil {
+		t.Fatalf("failed to get feedback: %v", err)
+	}
+	if len(got) != 2 || got[0].Tag != "cli" || got[0].Accepted || got[0].CreatedAt.IsZero() ||
+		got[1].Tag != "mit" || !got[1].Accepted {
+		t.Fatalf("got wrong feedback: %+v", got)
+	}
+
+	ps, err := db.GetPrecision()
+	if err != nil {
+		t.Fatalf("failed to get precision: %v", err)
+	}
+	expected := []storage.Precision{
+		{Rule: "readme", Accepted: 2, Rejected: 1, Precision: 2.0 / 3},
+		{Rule: "rules/license", Accepted: 1, Rejected: 0, Precision: 1},
+	}
+	if len(ps) != len(expected) {
+		t.Fatalf("expected %d rules; got %d", len(expected), len(ps))
+	}
+	for i, p := range ps {
+		if *p != expected[i] {
+			t.Errorf("expected %+v; got %+v", expected[i], p)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/rschio/repoTagger/storage"
+)
+
+// CreateJob stores the report of the job as JSON.
+func (s *service) CreateJob(j *storage.Job) error {
+	report, err := json.Marshal(j.Report)
+	if err != nil {
+		return err
+	}
+	j.CreatedAt = time.Now().UTC()
+	j.UpdatedAt = j.CreatedAt
+	stmt := `INSERT INTO job (status, dry_run, threshold, total, done, applied, report,
+		created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	res, err := s.DB.Exec(stmt, j.Status, j.DryRun, j.Threshold, j.Total, j.Done, j.Applied,
+		string(report), j.CreatedAt, j.UpdatedAt)
+	if err != nil {
+		log.Printf("failed to create job: %v", err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	j.ID = int(id)
+	return nil
+}
+
+func (s *service) UpdateJob(j *storage.Job) error {
+	report, err := json.Marshal(j.Report)
+	if err != nil {
+		return err
+	}
+	j.UpdatedAt = time.Now().UTC()
+	stmt := `UPDATE job SET status = ?, total = ?, done = ?, applied = ?, report = ?,
+		updated_at = ? WHERE id = ?;`
+	_, err = s.DB.Exec(stmt, j.Status, j.Total, j.Done, j.Applied, string(report),
+		j.UpdatedAt, j.ID)
+	if err != nil {
+		log.Printf("failed to update job %d: %v", j.ID, err)
+	}
+	return err
+}
+
+func (s *service) GetJob(id int) (*storage.Job, error) {
+	j := &storage.Job{}
+	var report string
+	stmt := `SELECT id, status, dry_run, threshold, total, done, applied, report,
+		created_at, updated_at FROM job WHERE id = ?;`
+	err := s.DB.QueryRow(stmt, id).Scan(&j.ID, &j.Status, &j.DryRun, &j.Threshold, &j.Total,
+		&j.Done, &j.Applied, &report, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(report), &j.Report); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (s *service) FailRunningJobs() (int, error) {
+	stmt := "UPDATE job SET status = ?, updated_at = ? WHERE status = ?;"
+	res, err := s.DB.Exec(stmt, storage.JobFailed, time.Now().UTC(), storage.JobRunning)
+	if err != nil {
+		log.Printf("failed to fail running jobs: %v", err)
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+func TestJob(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	j := &storage.Job{Status: storage.JobRunning, DryRun: true, Threshold: 0.8, Total: 2}
+	if err := db.CreateJob(j); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	if j.ID == 0 {
+		t.Fatalf("expected job id")
+	}
+
+	j.Done, j.Status = 2, storage.JobDone
+	j.Report = []*storage.JobResult{{RepoID: 1, FullName: "foo/bar",
+		Suggestions: []repo.Suggestion{{Tag: "cli", Score: 0.9, Rule: "readme"}}}}
+	if err := db.UpdateJob(j); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+
+	got, err := db.GetJob(j.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if got.Status != storage.JobDone || !got.DryRun || got.Threshold != 0.8 || got.Done != 2 ||
+		len(got.Report) != 1 || got.Report[0].Suggestions[0].Tag != "cli" ||
+		!got.CreatedAt.Equal(j.CreatedAt) {
+		t.Fatalf("got wrong job: %+v", got)
+	}
+
+	if _, err := db.GetJob(j.ID + 1); err == nil {
+		t.Fatalf("expected error of missing job")
+	}
+
+	running := &storage.Job{Status: storage.JobRunning, Total: 1}
+	if err := db.CreateJob(running); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	n, err := db.FailRunningJobs()
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 failed job; got %d, %v", n, err)
+	}
+	for _, tc := range []struct {
+		id     int
+		status string
+	}{{j.ID, storage.JobDone}, {running.ID, storage.JobFailed}} {
+		got, err := db.GetJob(tc.id)
+		if err != nil || got.Status != tc.status {
+			t.Fatalf("expected job %d %s; got %+v, %v", tc.id, tc.status, got, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+func (s *service) GetReadme(repoID int) (*repo.Readme, error) {
+	readme := &repo.Readme{}
+	stmt := "SELECT sha, text, etag FROM readme WHERE repo_id = ?;"
+	err := s.DB.QueryRow(stmt, repoID).Scan(&readme.SHA, &readme.Text, &readme.ETag)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("failed to get readme of repo %d: %v", repoID, err)
+		return nil, err
+	}
+	return readme, nil
+}
+
+// SetReadme replaces the README and its index, the
+// docid of readme_fts is the repo id.
+func (s *service) SetReadme(repoID int, readme *repo.Readme) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := "INSERT OR REPLACE INTO readme (repo_id, sha, text, etag) VALUES (?, ?, ?, ?);"
+	if _, err := tx.Exec(stmt, repoID, readme.SHA, readme.Text, readme.ETag); err != nil {
+		log.Printf("failed to set readme of repo %d: %v", repoID, err)
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM readme_fts WHERE docid = ?;", repoID); err != nil {
+		return err
+	}
+	stmt = "INSERT INTO readme_fts (docid, text) VALUES (?, ?);"
+	if _, err := tx.Exec(stmt, repoID, readme.Text); err != nil {
+		log.Printf("failed to index readme of repo %d: %v", repoID, err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteReadme deletes the README of the repo id and its index.
+func deleteReadme(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("DELETE FROM readme WHERE repo_id = ?;", id); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM readme_fts WHERE docid = ?;", id)
+	return err
+}
+
+// SearchText uses the query syntax of fts4, like
+// "command line" or dock*.
+func (s *service) SearchText(query string) ([]*repo.Repo, error) {
+	stmt := "SELECT " + repoColumns + ` FROM repo AS r
+		JOIN readme_fts AS f ON f.docid = r.id
+		WHERE readme_fts MATCH ? AND r.status != '` + repo.StatusArchived + `';`
+	rows, err := s.DB.Query(stmt, query)
+	if err != nil {
+		return nil, searchErr(query, err)
+	}
+	defer rows.Close()
+
+	repos := make([]*repo.Repo, 0)
+	for rows.Next() {
+		r, err := scanRepo(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.fillRepo(r); err != nil {
+			return nil, err
+		}
+		repos = append(repos, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, searchErr(query, err)
+	}
+	return repos, nil
+}
+
+// searchErr returns storage.QueryErr if err is of a malformed
+// query, fts4 may only find it while reading the rows.
+func searchErr(query string, err error) error {
+	if strings.HasPrefix(err.Error(), "malformed MATCH expression") {
+		return storage.QueryErr(err.Error())
+	}
+	log.Printf("failed to search text %q: %v", query, err)
+	return err
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+func TestReadme(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	for id := 1; id <= 2; id++ {
+		r := &repo.Repo{ID: id, Name: "Foo", URLHTTP: "http://something.com"}
+		if err := db.InsertRepo(r); err != nil {
+			t.Fatalf("failed to insert repo: %v", err)
+		}
+	}
+
+	readme, err := db.GetReadme(1)
+	if err != nil || readme != nil {
+		t.Fatalf("expected no readme; got %v, %v", readme, err)
+	}
+
+	if err := db.SetReadme(1, &repo.Readme{SHA: "a", Text: "Manage running containers"}); err != nil {
+		t.Fatalf("failed to set readme: %v", err)
+	}
+	if err := db.SetReadme(2, &repo.Readme{SHA: "b", Text: "A command line parser"}); err != nil {
+		t.Fatalf("failed to set readme: %v", err)
+	}
+	// the old text is not indexed.
+	if err := db.SetReadme(2, &repo.Readme{SHA: "c", Text: "A web router", ETag: `"c"`}); err != nil {
+		t.Fatalf("failed to set readme: %v", err)
+	}
+
+	readme, err = db.GetReadme(2)
+	if err != nil || readme.SHA != "c" || readme.Text != "A web router" || readme.ETag != `"c"` {
+		t.Fatalf("got wrong readme: %v, %v", readme, err)
+	}
+
+	tt := []struct {
+		query string
+		ids   []int
+	}{
+		{"container", []int{1}},
+		{"run", []int{1}},
+		{"router", []int{2}},
+		{"parser", []int{}},
+	}
+	for _, tc := range tt {
+		rs, err := db.SearchText(tc.query)
+		if err != nil {
+			t.Fatalf("failed to search %q: %v", tc.query, err)
+		}
+		if len(rs) != len(tc.ids) {
+			t.Fatalf("expected %d repos matching %q; got %d", len(tc.ids), tc.query, len(rs))
+		}
+		for i, r := range rs {
+			if r.ID != tc.ids[i] {
+				t.Errorf("expected repo %d matching %q; got %d", tc.ids[i], tc.query, r.ID)
+			}
+		}
+	}
+
+	_, err = db.SearchText(`"command line`)
+	if _, ok := err.(storage.QueryErr); !ok {
+		t.Fatalf("expected query error; got %v", err)
+	}
+}
@@ -28,11 +28,179 @@ func createDB(database *sql.DB) error {
 			name TEXT NOT NULL,
 			repo_id INTEGER NOT NULL
 		);
+		CREATE TABLE IF NOT EXISTS topic (
+			name TEXT NOT NULL,
+			repo_id INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS system_tag (
+			name TEXT NOT NULL,
+			repo_id INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS user_repo (
+			user TEXT NOT NULL,
+			collection TEXT NOT NULL,
+			repo_id INTEGER NOT NULL,
+			status TEXT NOT NULL
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS user_repo_idx
+			ON user_repo (user, collection, repo_id);
+		CREATE TABLE IF NOT EXISTS sync_state (
+			user TEXT PRIMARY KEY,
+			last_starred_at TIMESTAMP,
+			etag TEXT
+		);
+		CREATE TABLE IF NOT EXISTS readme (
+			repo_id INTEGER PRIMARY KEY,
+			sha TEXT NOT NULL,
+			text TEXT NOT NULL,
+			etag TEXT NOT NULL DEFAULT ''
+		);
+		CREATE VIRTUAL TABLE IF NOT EXISTS readme_fts USING fts4(text, tokenize=porter);
+		CREATE TABLE IF NOT EXISTS job (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			status TEXT NOT NULL,
+			dry_run BOOLEAN NOT NULL,
+			threshold REAL NOT NULL,
+			total INTEGER NOT NULL,
+			done INTEGER NOT NULL,
+			applied INTEGER NOT NULL,
+			report TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS feedback (
+			repo_id INTEGER NOT NULL,
+			tag TEXT NOT NULL,
+			rule TEXT NOT NULL,
+			accepted BOOLEAN NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (repo_id, tag)
+		);
 	`
 	_, err := database.Exec(stmt)
+	if err != nil {
+		return err
+	}
+	return migrate(database)
+}
+
+// columns are the columns added after the table creation,
+// they are added to databases created by older versions.
+var columns = []struct {
+	table, name, def string
+}{
+	{"repo", "starred_at", "TIMESTAMP"},
+	{"repo", "status", "TEXT NOT NULL DEFAULT ''"},
+	{"repo", "source", "TEXT NOT NULL DEFAULT '" + repo.SourceGitHub + "'"},
+	{"repo", "source_id", "INTEGER"},
+	{"repo", "owner", "TEXT NOT NULL DEFAULT ''"},
+	{"repo", "full_name", "TEXT NOT NULL DEFAULT ''"},
+	{"repo", "stars", "INTEGER NOT NULL DEFAULT 0"},
+	{"repo", "forks", "INTEGER NOT NULL DEFAULT 0"},
+	{"repo", "archived", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"repo", "fork", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"repo", "license", "TEXT NOT NULL DEFAULT ''"},
+	{"repo", "owner_type", "TEXT NOT NULL DEFAULT ''"},
+	{"repo", "created_at", "TIMESTAMP"},
+	{"repo", "pushed_at", "TIMESTAMP"},
+	{"repo", "updated_at", "TIMESTAMP"},
+	{"repo", "open_issues", "INTEGER NOT NULL DEFAULT 0"},
+	{"repo", "released_at", "TIMESTAMP"},
+	{"user_repo", "collection", "TEXT NOT NULL DEFAULT '" + repo.CollectionStarred + "'"},
+}
+
+// added are run once, when their column is added to a
+// database created by an older version. A repo can be in
+// many collections of the same user.
+var added = map[string]string{
+	"user_repo.collection": `
+		DROP INDEX IF EXISTS user_repo_idx;
+		CREATE UNIQUE INDEX user_repo_idx ON user_repo (user, collection, repo_id);`,
+}
+
+// afterMigrate fills and indexes the added columns. The
+// full name of old GitHub repos comes from their URL.
+const afterMigrate = `
+	UPDATE repo SET source_id = id WHERE source_id IS NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS repo_source_idx ON repo (source, source_id);
+	UPDATE repo SET full_name = substr(url_http, length('https://github.com/') + 1)
+		WHERE full_name = '' AND source = 'github' AND url_http LIKE 'https://github.com/%/%';
+	UPDATE repo SET owner = substr(full_name, 1, instr(full_name, '/') - 1)
+		WHERE owner = '' AND instr(full_name, '/') > 0;
+`
+
+// migrate adds the missing columns to the tables.
+func migrate(database *sql.DB) error {
+	for _, c := range columns {
+		ok, err := hasColumn(database, c.table, c.name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		stmt := "ALTER TABLE " + c.table + " ADD COLUMN " + c.name + " " + c.def + ";"
+		if _, err := database.Exec(stmt); err != nil {
+			log.Printf("failed to add column %s to %s: %v", c.name, c.table, err)
+			return err
+		}
+		if stmt, ok := added[c.table+"."+c.name]; ok {
+			if _, err := database.Exec(stmt); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := database.Exec(afterMigrate)
 	return err
 }
 
+func hasColumn(database *sql.DB, table, column string) (bool, error) {
+	rows, err := database.Query("SELECT name FROM pragma_table_info(?);", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// repoColumns are the columns read by scanRepo.
+const repoColumns = `r.id, r.name, r.desc, r.url_http, r.lang, r.starred_at, r.status,
+	r.source, r.source_id, r.owner, r.full_name, r.stars, r.forks, r.archived, r.fork,
+	r.license, r.owner_type, r.created_at, r.pushed_at, r.updated_at, r.open_issues,
+	r.released_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRepo reads a repo selected with repoColumns.
+func scanRepo(row scanner) (*repo.Repo, error) {
+	r := &repo.Repo{}
+	// the times are null in repos stored by older versions.
+	var starredAt, createdAt, pushedAt, updatedAt, releasedAt sql.NullTime
+	err := row.Scan(&r.ID, &r.Name, &r.Desc, &r.URLHTTP, &r.Lang, &starredAt, &r.Status,
+		&r.Source, &r.SourceID, &r.Owner, &r.FullName, &r.Stars, &r.Forks, &r.Archived,
+		&r.Fork, &r.License, &r.OwnerType, &createdAt, &pushedAt, &updatedAt, &r.OpenIssues,
+		&releasedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.StarredAt = starredAt.Time
+	r.CreatedAt, r.PushedAt, r.UpdatedAt = createdAt.Time, pushedAt.Time, updatedAt.Time
+	r.ReleasedAt = releasedAt.Time
+	return r, nil
+}
+
 // New returns a new storage with a sqlite database
 // of path db. If db does not exists New create the
 // file and tables repo and tag.
@@ -79,42 +247,194 @@ func (s *service) UpdateTags(r *repo.Repo) error {
 }
 
 func (s *service) InsertRepo(r *repo.Repo) error {
-	stmt := "INSERT INTO repo (id, name, desc, url_http, lang) VALUES (?, ?, ?, ?, ?);"
-	_, err := s.DB.Exec(stmt, r.ID, r.Name, r.Desc, r.URLHTTP, r.Lang)
+	return s.insertRepo(r, r.ID)
+}
+
+// setSource sets the default source of r, GitHub
+// repositories have the same id in the catalog.
+func setSource(r *repo.Repo) {
+	if r.Source == "" {
+		r.Source = repo.SourceGitHub
+	}
+	if r.SourceID == 0 && r.Source == repo.SourceGitHub {
+		r.SourceID = r.ID
+	}
+}
+
+// insertRepo inserts r with id, a nil id creates a new one
+// that is set to r.
+func (s *service) insertRepo(r *repo.Repo, id interface{}) error {
+	setSource(r)
+	stmt := `INSERT INTO repo (id, name, desc, url_http, lang, starred_at, source, source_id,
+		owner, full_name, stars, forks, archived, fork, license, owner_type, created_at,
+		pushed_at, updated_at, open_issues)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	res, err := s.DB.Exec(stmt, id, r.Name, r.Desc, r.URLHTTP, r.Lang, r.StarredAt,
+		r.Source, r.SourceID, r.Owner, r.FullName, r.Stars, r.Forks, r.Archived, r.Fork,
+		r.License, r.OwnerType, r.CreatedAt, r.PushedAt, r.UpdatedAt, r.OpenIssues)
 	if err != nil {
 		log.Printf("failed to insert repo %s: %v", r.Name, err)
 		return err
 	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	r.ID = int(lastID)
+	if err := s.setTopics(r); err != nil {
+		return err
+	}
 	return s.insertTags(r)
 }
 
-func (s *service) GetRepo(id int) (*repo.Repo, error) {
-	stmt := "SELECT id, name, desc, url_http, lang FROM repo WHERE id = ?;"
-	row := s.DB.QueryRow(stmt, id)
-	r := &repo.Repo{}
+// UpsertRepo finds the stored repo by its source and source id.
+// New repos keep their id if no other repo has it.
+func (s *service) UpsertRepo(r *repo.Repo) (storage.UpsertResult, error) {
+	setSource(r)
+	var id int
+	stmt := "SELECT id FROM repo WHERE source = ? AND source_id = ?;"
+	err := s.DB.QueryRow(stmt, r.Source, r.SourceID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return s.insertNewRepo(r)
+	}
+	if err != nil {
+		return 0, err
+	}
 
-	err := row.Scan(&r.ID, &r.Name, &r.Desc, &r.URLHTTP, &r.Lang)
+	r.ID = id
+	stored, err := s.GetRepo(id)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	// a repo without starred_at keeps the stored one.
+	starredAt := r.StarredAt
+	if starredAt.IsZero() {
+		starredAt = stored.StarredAt
+	}
+	if stored.Name == r.Name && stored.Desc == r.Desc && stored.URLHTTP == r.URLHTTP &&
+		stored.Lang == r.Lang && stored.StarredAt.Equal(starredAt) &&
+		stored.Owner == r.Owner && stored.FullName == r.FullName &&
+		repo.NamesEq(stored.Topics, r.Topics) && metadataEq(stored, r) {
+		return storage.Unchanged, nil
 	}
 
-	r.Tags, err = s.getTags(id)
+	if err := s.setTopics(r); err != nil {
+		return 0, err
+	}
+
+	stmt = `UPDATE repo SET name = ?, desc = ?, url_http = ?, lang = ?, starred_at = ?,
+		owner = ?, full_name = ?, stars = ?, forks = ?, archived = ?, fork = ?, license = ?,
+		owner_type = ?, created_at = ?, pushed_at = ?, updated_at = ?, open_issues = ?
+		WHERE id = ?;`
+	_, err = s.DB.Exec(stmt, r.Name, r.Desc, r.URLHTTP, r.Lang, starredAt,
+		r.Owner, r.FullName, r.Stars, r.Forks, r.Archived, r.Fork, r.License,
+		r.OwnerType, r.CreatedAt, r.PushedAt, r.UpdatedAt, r.OpenIssues, r.ID)
+	if err != nil {
+		log.Printf("failed to update repo %s: %v", r.Name, err)
+		return 0, err
+	}
+	return storage.Updated, nil
+}
+
+func (s *service) insertNewRepo(r *repo.Repo) (storage.UpsertResult, error) {
+	var id interface{}
+	if r.ID != 0 {
+		var taken int
+		err := s.DB.QueryRow("SELECT COUNT(*) FROM repo WHERE id = ?;", r.ID).Scan(&taken)
+		if err != nil {
+			return 0, err
+		}
+		if taken == 0 {
+			id = r.ID
+		}
+	}
+	if err := s.insertRepo(r, id); err != nil {
+		return 0, err
+	}
+	return storage.Inserted, nil
+}
+
+func (s *service) GetRepo(id int) (*repo.Repo, error) {
+	stmt := "SELECT " + repoColumns + " FROM repo AS r WHERE r.id = ?;"
+	r, err := scanRepo(s.DB.QueryRow(stmt, id))
 	if err != nil {
 		return nil, err
 	}
+
+	if err := s.fillRepo(r); err != nil {
+		return nil, err
+	}
 	return r, nil
 }
 
+// fillRepo reads the tags, topics and collections of r.
+func (s *service) fillRepo(r *repo.Repo) error {
+	var err error
+	r.Tags, err = s.getTags(r.ID)
+	if err != nil {
+		return err
+	}
+	r.Topics, err = s.getNames("topic", r.ID)
+	if err != nil {
+		return err
+	}
+	r.SystemTags, err = s.getNames("system_tag", r.ID)
+	if err != nil {
+		return err
+	}
+	r.Collections, err = s.getCollections(r.ID)
+	return err
+}
+
+// getCollections returns the collections that
+// have the repo repoID.
+func (s *service) getCollections(repoID int) ([]string, error) {
+	stmt := `SELECT DISTINCT collection FROM user_repo WHERE repo_id = ? AND status != ?
+		ORDER BY collection;`
+	rows, err := s.DB.Query(stmt, repoID, unstarred)
+	if err != nil {
+		log.Printf("failed to get collections of repo %d: %v", repoID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := make([]string, 0)
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
 func (s *service) GetReposByTag(tag string) ([]*repo.Repo, error) {
-	stmt := `SELECT r.id, r.name, r.desc, r.url_http, r.lang FROM 
-		repo AS r JOIN tag WHERE tag.repo_id = r.id AND tag.name LIKE ? || '%';`
+	return s.GetReposByTagFrom(tag, "")
+}
+
+func (s *service) GetReposByTagFrom(tag, collection string) ([]*repo.Repo, error) {
+	stmt := "SELECT " + repoColumns + ` FROM repo AS r
+		WHERE r.status != '` + repo.StatusArchived + `' AND (
+		r.id IN (SELECT repo_id FROM tag WHERE name LIKE ? || '%') OR
+		r.id IN (SELECT repo_id FROM topic WHERE name LIKE ? || '%') OR
+		r.id IN (SELECT repo_id FROM system_tag WHERE name LIKE ? || '%'))`
+	args := []interface{}{tag, tag, tag}
 
 	// get all repos.
 	if tag == "" {
-		stmt = "SELECT id, name, desc, url_http, lang FROM repo;"
+		stmt = "SELECT " + repoColumns + " FROM repo AS r WHERE r.status != '" +
+			repo.StatusArchived + "'"
+		args = nil
+	}
+	if collection != "" {
+		stmt += ` AND r.id IN (SELECT repo_id FROM user_repo
+			WHERE collection = ? AND status != '` + unstarred + `')`
+		args = append(args, collection)
 	}
 
-	rows, err := s.DB.Query(stmt, tag)
+	rows, err := s.DB.Query(stmt+";", args...)
 	if err != nil {
 		log.Printf("failed to get repos: %v", err)
 		return nil, err
@@ -129,9 +449,7 @@ func (s *service) GetReposByTag(tag string) ([]*repo.Repo, error) {
 	repos := make([]*repo.Repo, 0)
 
 	for rows.Next() {
-		r := &repo.Repo{}
-
-		err = rows.Scan(&r.ID, &r.Name, &r.Desc, &r.URLHTTP, &r.Lang)
+		r, err := scanRepo(rows)
 		if err != nil {
 			log.Printf("failed to get repo IDs: %v", err)
 			return nil, err
@@ -141,8 +459,7 @@ func (s *service) GetReposByTag(tag string) ([]*repo.Repo, error) {
 			continue
 		}
 
-		r.Tags, err = s.getTags(r.ID)
-		if err != nil {
+		if err := s.fillRepo(r); err != nil {
 			return nil, err
 		}
 
@@ -158,14 +475,21 @@ func (s *service) GetReposByTag(tag string) ([]*repo.Repo, error) {
 }
 
 func (s *service) getTags(repoID int) ([]string, error) {
-	stmt := "SELECT name FROM tag WHERE repo_id = ?;"
+	return s.getNames("tag", repoID)
+}
+
+// getNames returns the names of table, tag, topic or
+// system_tag, of the repo repoID.
+func (s *service) getNames(table string, repoID int) ([]string, error) {
+	stmt := "SELECT name FROM " + table + " WHERE repo_id = ? ORDER BY rowid;"
 	tags := make([]string, 0)
 
 	rows, err := s.DB.Query(stmt, repoID)
 	if err != nil {
-		log.Printf("failed to get tags from repo %d: %v", repoID, err)
+		log.Printf("failed to get %ss from repo %d: %v", table, repoID, err)
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var s string
@@ -183,3 +507,56 @@ func (s *service) getTags(repoID int) ([]string, error) {
 
 	return tags, nil
 }
+
+// setTopics replaces the topics of r.
+func (s *service) setTopics(r *repo.Repo) error {
+	_, err := s.DB.Exec("DELETE FROM topic WHERE repo_id = ?;", r.ID)
+	if err != nil {
+		return err
+	}
+	stmt := "INSERT INTO topic (name, repo_id) VALUES (?, ?);"
+	for _, topic := range r.Topics {
+		if _, err := s.DB.Exec(stmt, topic, r.ID); err != nil {
+			log.Printf("failed to insert topic %s: %v", topic, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataEq reports whether r1 and r2 have the same
+// metadata of the source.
+func metadataEq(r1, r2 *repo.Repo) bool {
+	return r1.Stars == r2.Stars && r1.Forks == r2.Forks && r1.Archived == r2.Archived &&
+		r1.Fork == r2.Fork && r1.License == r2.License && r1.OwnerType == r2.OwnerType &&
+		r1.CreatedAt.Equal(r2.CreatedAt) && r1.PushedAt.Equal(r2.PushedAt) &&
+		r1.UpdatedAt.Equal(r2.UpdatedAt) && r1.OpenIssues == r2.OpenIssues
+}
+
+func (s *service) GetSyncState(user string) (*repo.SyncState, error) {
+	stmt := "SELECT last_starred_at, etag FROM sync_state WHERE user = ?;"
+	state := &repo.SyncState{User: user}
+	var lastStarredAt sql.NullTime
+	var etag sql.NullString
+
+	err := s.DB.QueryRow(stmt, user).Scan(&lastStarredAt, &etag)
+	if err == sql.ErrNoRows {
+		// never synced.
+		return state, nil
+	}
+	if err != nil {
+		log.Printf("failed to get sync state of %s: %v", user, err)
+		return nil, err
+	}
+	state.LastStarredAt, state.ETag = lastStarredAt.Time, etag.String
+	return state, nil
+}
+
+func (s *service) SetSyncState(state *repo.SyncState) error {
+	stmt := "INSERT OR REPLACE INTO sync_state (user, last_starred_at, etag) VALUES (?, ?, ?);"
+	_, err := s.DB.Exec(stmt, state.User, state.LastStarredAt, state.ETag)
+	if err != nil {
+		log.Printf("failed to set sync state of %s: %v", state.User, err)
+	}
+	return err
+}
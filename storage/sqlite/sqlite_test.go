@@ -1,11 +1,15 @@
 package sqlite
 
 import (
+	"database/sql"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
 )
 
 func TestNew(t *testing.T) {
@@ -26,6 +30,48 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestMigrate(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	// database created by the first version.
+	database, err := sql.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = database.Exec(`CREATE TABLE repo (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			desc TEXT,
+			url_http TEXT NOT NULL,
+			lang TEXT
+		);
+		INSERT INTO repo (id, name, desc, url_http, lang)
+		VALUES (1, "Foo", "", "https://github.com/bar/Foo", "go");`)
+	if err != nil {
+		t.Fatalf("failed to create old table: %v", err)
+	}
+	database.Close()
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	defer db.(*service).Close()
+
+	r, err := db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get old repo: %v", err)
+	}
+	if r.Name != "Foo" || !r.StarredAt.IsZero() || r.Source != repo.SourceGitHub ||
+		r.SourceID != 1 || r.Owner != "bar" || r.FullName != "bar/Foo" {
+		t.Fatalf("got wrong repo: %+v", r)
+	}
+}
+
 func tagsEq(t1, t2 []string) bool {
 	if len(t1) != len(t2) {
 		return false
@@ -58,8 +104,10 @@ func TestInsertGet(t *testing.T) {
 		t.Errorf("database should be created")
 	}
 
-	r1 := &repo.Repo{0, "Foo", "decrpition", "http://something.com", "go", []string{"H", "e"}}
-	r2 := &repo.Repo{4, "Bar", "ha", "http://something.com", "", []string{}}
+	r1 := &repo.Repo{ID: 0, Name: "Foo", Desc: "decrpition", URLHTTP: "http://something.com",
+		Lang: "go", Tags: []string{"H", "e"}}
+	r2 := &repo.Repo{ID: 4, Name: "Bar", Desc: "ha", URLHTTP: "http://something.com",
+		Tags: []string{}}
 	err = db.InsertRepo(r1)
 	if err != nil {
 		t.Errorf("failed to insert repo: %v", err)
@@ -107,9 +155,10 @@ func TestGetReposByTag(t *testing.T) {
 		t.Errorf("database should be created")
 	}
 
-	r1 := &repo.Repo{0, "Foo", "decrpition", "http://something.com", "go",
-		[]string{"document", "docker"}}
-	r2 := &repo.Repo{4, "Bar", "ha", "http://something.com", "", []string{}}
+	r1 := &repo.Repo{ID: 0, Name: "Foo", Desc: "decrpition", URLHTTP: "http://something.com",
+		Lang: "go", Tags: []string{"document", "docker"}}
+	r2 := &repo.Repo{ID: 4, Name: "Bar", Desc: "ha", URLHTTP: "http://something.com",
+		Tags: []string{}}
 	db.InsertRepo(r1)
 	db.InsertRepo(r2)
 
@@ -153,9 +202,10 @@ func TestUpdateTags(t *testing.T) {
 		t.Errorf("database should be created")
 	}
 
-	r1 := &repo.Repo{0, "Foo", "decrpition", "http://something.com", "go",
-		[]string{"document", "docker"}}
-	r2 := &repo.Repo{4, "Bar", "ha", "http://something.com", "", []string{}}
+	r1 := &repo.Repo{ID: 0, Name: "Foo", Desc: "decrpition", URLHTTP: "http://something.com",
+		Lang: "go", Tags: []string{"document", "docker"}}
+	r2 := &repo.Repo{ID: 4, Name: "Bar", Desc: "ha", URLHTTP: "http://something.com",
+		Tags: []string{}}
 	db.InsertRepo(r1)
 	db.InsertRepo(r2)
 
@@ -196,3 +246,256 @@ func TestUpdateTags(t *testing.T) {
 	}
 
 }
+
+func TestUpsertRepo(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Errorf("database should be created")
+	}
+
+	r1 := &repo.Repo{ID: 1, Name: "Foo", Desc: "old", URLHTTP: "http://something.com",
+		Lang: "go", Tags: []string{"mine"}}
+	r2 := &repo.Repo{ID: 2, Name: "Bar", URLHTTP: "http://something.com"}
+	count, stored := storage.UpsertRepos(db, []*repo.Repo{r1, r2})
+	if *count != (storage.UpsertCount{Inserted: 2}) || len(stored) != 2 {
+		t.Fatalf("expected 2 inserted; got %+v", count)
+	}
+
+	// imported again without the user tags.
+	r1 = &repo.Repo{ID: 1, Name: "Foo", Desc: "new", URLHTTP: "http://something.com",
+		Lang: "go"}
+	r2 = &repo.Repo{ID: 2, Name: "Bar", URLHTTP: "http://something.com"}
+	r3 := &repo.Repo{ID: 3, Name: "Baz", URLHTTP: "http://something.com"}
+	count, _ = storage.UpsertRepos(db, []*repo.Repo{r1, r2, r3})
+	if *count != (storage.UpsertCount{Inserted: 1, Updated: 1, Unchanged: 1}) {
+		t.Fatalf("expected 1 inserted, updated and unchanged; got %+v", count)
+	}
+
+	got, err := db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if got.Desc != "new" || !tagsEq(got.Tags, []string{"mine"}) {
+		t.Fatalf("expected updated repo with its tags; got %v", got)
+	}
+}
+
+// failingStore fails to upsert the repository fail.
+type failingStore struct {
+	storage.Storage
+	fail int
+}
+
+func (s failingStore) UpsertRepo(r *repo.Repo) (storage.UpsertResult, error) {
+	if r.ID == s.fail {
+		return 0, errors.New("upsert failed")
+	}
+	return s.Storage.UpsertRepo(r)
+}
+
+func TestUpsertReposFailed(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	r1 := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com"}
+	r2 := &repo.Repo{ID: 2, Name: "Bar", URLHTTP: "http://something.com"}
+	count, stored := storage.UpsertRepos(failingStore{db, 1}, []*repo.Repo{r1, r2})
+	if *count != (storage.UpsertCount{Inserted: 1, Failed: 1}) {
+		t.Fatalf("expected 1 inserted and failed; got %+v", count)
+	}
+	if len(stored) != 1 || stored[0].ID != 2 {
+		t.Fatalf("expected only repo 2 stored; got %v", stored)
+	}
+}
+
+func TestUpsertSources(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Errorf("database should be created")
+	}
+
+	gh1 := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com"}
+	other := &repo.Repo{Name: "Bar", URLHTTP: "http://something.com",
+		Source: "other", SourceID: 1}
+	// the GitHub id was given to other.
+	gh2 := &repo.Repo{ID: 2, Name: "Baz", URLHTTP: "http://something.com"}
+	count, _ := storage.UpsertRepos(db, []*repo.Repo{gh1, other, gh2})
+	if *count != (storage.UpsertCount{Inserted: 3}) {
+		t.Fatalf("expected 3 inserted; got %+v", count)
+	}
+	if gh1.ID != 1 || other.ID != 2 || gh2.ID != 3 {
+		t.Fatalf("expected ids 1, 2 and 3; got %d, %d and %d", gh1.ID, other.ID, gh2.ID)
+	}
+
+	again := &repo.Repo{Name: "Bar", URLHTTP: "http://something.com",
+		Source: "other", SourceID: 1}
+	res, err := db.UpsertRepo(again)
+	if err != nil || res != storage.Unchanged || again.ID != other.ID {
+		t.Fatalf("expected unchanged repo %d; got %d, %v, %v", other.ID, again.ID, res, err)
+	}
+
+	r, err := db.GetRepo(gh2.ID)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if r.Source != repo.SourceGitHub || r.SourceID != 2 {
+		t.Fatalf("expected github repo 2; got %s repo %d", r.Source, r.SourceID)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Errorf("database should be created")
+	}
+
+	pushedAt := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	r := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com", Stars: 1500,
+		Forks: 20, Fork: true, License: "mit", OwnerType: "User", OpenIssues: 7,
+		CreatedAt: pushedAt.Add(-time.Hour), PushedAt: pushedAt, UpdatedAt: pushedAt}
+	if _, err := db.UpsertRepo(r); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+
+	got, err := db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if got.Stars != 1500 || got.Forks != 20 || got.Archived || !got.Fork ||
+		got.License != "mit" || got.OwnerType != "User" || got.OpenIssues != 7 ||
+		!got.CreatedAt.Equal(r.CreatedAt) || !got.PushedAt.Equal(pushedAt) {
+		t.Fatalf("got wrong metadata: %+v", got)
+	}
+
+	archived := *r
+	archived.Archived = true
+	res, err := db.UpsertRepo(&archived)
+	if err != nil || res != storage.Updated {
+		t.Fatalf("expected updated repo; got %v, %v", res, err)
+	}
+	got, err = db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if !got.Archived {
+		t.Fatalf("expected archived repo")
+	}
+}
+
+func TestTopics(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Errorf("database should be created")
+	}
+
+	r1 := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com",
+		Tags: []string{"mine"}, Topics: []string{"docker", "go"}}
+	r2 := &repo.Repo{ID: 2, Name: "Bar", URLHTTP: "http://something.com",
+		Tags: []string{"dock"}}
+	storage.UpsertRepos(db, []*repo.Repo{r1, r2})
+
+	rs, err := db.GetReposByTag("doc")
+	if err != nil {
+		t.Fatalf("failed to get repos by tag: %v", err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected repos by tag and topic; got %d", len(rs))
+	}
+
+	// the maintainer changed the topics.
+	r1 = &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com",
+		Topics: []string{"go", "cli"}}
+	res, err := db.UpsertRepo(r1)
+	if err != nil || res != storage.Updated {
+		t.Fatalf("expected updated repo; got %v, %v", res, err)
+	}
+	got, err := db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if !tagsEq(got.Topics, []string{"go", "cli"}) || !tagsEq(got.Tags, []string{"mine"}) {
+		t.Fatalf("expected new topics and old tags; got %v and %v", got.Topics, got.Tags)
+	}
+}
+
+func TestSyncState(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Errorf("database should be created")
+	}
+
+	state, err := db.GetSyncState("foo")
+	if err != nil {
+		t.Fatalf("failed to get sync state: %v", err)
+	}
+	if state.User != "foo" || !state.LastStarredAt.IsZero() || state.ETag != "" {
+		t.Fatalf("expected empty state; got %v", state)
+	}
+
+	state.LastStarredAt = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	state.ETag = `"abc"`
+	for i := 0; i < 2; i++ {
+		if err := db.SetSyncState(state); err != nil {
+			t.Fatalf("failed to set sync state: %v", err)
+		}
+	}
+
+	got, err := db.GetSyncState("foo")
+	if err != nil {
+		t.Fatalf("failed to get sync state: %v", err)
+	}
+	if !got.LastStarredAt.Equal(state.LastStarredAt) || got.ETag != state.ETag {
+		t.Fatalf("expected state %v; got %v", state, got)
+	}
+
+	r := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com",
+		StarredAt: state.LastStarredAt}
+	if err := db.InsertRepo(r); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	r2, err := db.GetRepo(r.ID)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if !r2.StarredAt.Equal(r.StarredAt) {
+		t.Fatalf("expected starred at %v; got %v", r.StarredAt, r2.StarredAt)
+	}
+}
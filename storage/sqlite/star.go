@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+// status of the repos in user_repo.
+const (
+	starred   = "starred"
+	unstarred = "unstarred"
+	// listed repos are in other collections than starred.
+	listed = "listed"
+)
+
+func (s *service) SetStarred(user string, ids []int) error {
+	return s.SetCollection(user, repo.CollectionStarred, ids)
+}
+
+func (s *service) SetCollection(user, collection string, ids []int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// only the starred collection can be unstarred.
+	status := listed
+	if collection == repo.CollectionStarred {
+		status = starred
+	}
+	for _, id := range ids {
+		stmt := `INSERT OR REPLACE INTO user_repo (user, collection, repo_id, status)
+			VALUES (?, ?, ?, ?);`
+		if _, err := tx.Exec(stmt, user, collection, id, status); err != nil {
+			log.Printf("failed to set repo %d in %s of %s: %v", id, collection, user, err)
+			return err
+		}
+		stmt = "UPDATE repo SET status = '' WHERE id = ?;"
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *service) Unstar(user string, starredIDs []int, policy storage.UnstarPolicy) ([]int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stored, err := starredBy(tx, user)
+	if err != nil {
+		return nil, err
+	}
+	isStarred := make(map[int]struct{}, len(starredIDs))
+	for _, id := range starredIDs {
+		isStarred[id] = struct{}{}
+	}
+
+	unstarredIDs := make([]int, 0)
+	for _, id := range stored {
+		if _, ok := isStarred[id]; ok {
+			continue
+		}
+		unstarredIDs = append(unstarredIDs, id)
+		if err := unstar(tx, user, id, policy); err != nil {
+			log.Printf("failed to unstar repo %d of %s: %v", id, user, err)
+			return nil, err
+		}
+	}
+
+	return unstarredIDs, tx.Commit()
+}
+
+// starredBy returns the ids of the repos starred by user.
+func starredBy(tx *sql.Tx, user string) ([]int, error) {
+	stmt := "SELECT repo_id FROM user_repo WHERE user = ? AND collection = ? AND status = ?;"
+	rows, err := tx.Query(stmt, user, repo.CollectionStarred, starred)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// unstar applies policy to the repo id unstarred by user.
+func unstar(tx *sql.Tx, user string, id int, policy storage.UnstarPolicy) error {
+	var err error
+	if policy == storage.Delete {
+		stmt := "DELETE FROM user_repo WHERE user = ? AND collection = ? AND repo_id = ?;"
+		_, err = tx.Exec(stmt, user, repo.CollectionStarred, id)
+	} else {
+		stmt := "UPDATE user_repo SET status = ? WHERE user = ? AND collection = ? AND repo_id = ?;"
+		_, err = tx.Exec(stmt, unstarred, user, repo.CollectionStarred, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	// other users still star the repo or
+	// it is in other collections.
+	var others int
+	stmt := "SELECT COUNT(*) FROM user_repo WHERE repo_id = ? AND status != ?;"
+	if err := tx.QueryRow(stmt, id, unstarred).Scan(&others); err != nil {
+		return err
+	}
+	if others > 0 {
+		return nil
+	}
+
+	if policy == storage.Archive {
+		_, err := tx.Exec("UPDATE repo SET status = ? WHERE id = ?;", repo.StatusArchived, id)
+		return err
+	}
+
+	// delete the repo only if no user has it, the rows
+	// left unstarred by other users mark it.
+	var users int
+	stmt = "SELECT COUNT(*) FROM user_repo WHERE repo_id = ?;"
+	if err := tx.QueryRow(stmt, id).Scan(&users); err != nil {
+		return err
+	}
+	if policy == storage.Mark || users > 0 {
+		_, err := tx.Exec("UPDATE repo SET status = ? WHERE id = ?;", repo.StatusUnstarred, id)
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM tag WHERE repo_id = ?;", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM topic WHERE repo_id = ?;", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM system_tag WHERE repo_id = ?;", id); err != nil {
+		return err
+	}
+	if err := deleteReadme(tx, id); err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM repo WHERE id = ?;", id)
+	return err
+}
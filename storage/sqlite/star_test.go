@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rschio/repoTagger/repo"
+	"github.com/rschio/repoTagger/storage"
+)
+
+func TestUnstar(t *testing.T) {
+	tt := []struct {
+		name   string
+		policy storage.UnstarPolicy
+		status string
+		found  bool
+	}{
+		{"mark", storage.Mark, repo.StatusUnstarred, true},
+		{"archive", storage.Archive, repo.StatusArchived, false},
+		{"delete", storage.Delete, "", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ioutil.TempFile(".", "testNewDb")
+			if err != nil {
+				t.Fatalf("failed to create temp file")
+			}
+			defer os.Remove(f.Name())
+
+			db, err := New(f.Name())
+			if err != nil {
+				t.Fatalf("database should be created")
+			}
+
+			for id := 1; id <= 3; id++ {
+				r := &repo.Repo{ID: id, Name: "Foo", URLHTTP: "http://something.com",
+					Tags: []string{"tag"}}
+				if err := db.InsertRepo(r); err != nil {
+					t.Fatalf("failed to insert repo: %v", err)
+				}
+			}
+			if err := db.SetStarred("foo", []int{1, 2, 3}); err != nil {
+				t.Fatalf("failed to set starred: %v", err)
+			}
+			// repo 3 is starred by other user too.
+			if err := db.SetStarred("bar", []int{3}); err != nil {
+				t.Fatalf("failed to set starred: %v", err)
+			}
+
+			ids, err := db.Unstar("foo", []int{1}, tc.policy)
+			if err != nil {
+				t.Fatalf("failed to unstar: %v", err)
+			}
+			if len(ids) != 2 || ids[0]+ids[1] != 5 {
+				t.Fatalf("expected repos 2 and 3 unstarred; got %v", ids)
+			}
+
+			// already reconciled.
+			ids, err = db.Unstar("foo", []int{1}, tc.policy)
+			if err != nil {
+				t.Fatalf("failed to unstar: %v", err)
+			}
+			if len(ids) != 0 {
+				t.Fatalf("expected no repos unstarred; got %v", ids)
+			}
+
+			r, err := db.GetRepo(2)
+			if tc.policy == storage.Delete {
+				if err == nil {
+					t.Fatalf("repo should be deleted")
+				}
+			} else if err != nil || r.Status != tc.status || len(r.Tags) != 1 {
+				t.Fatalf("expected repo with status %q and tags; got %v, %v", tc.status, r, err)
+			}
+
+			r, err = db.GetRepo(3)
+			if err != nil || r.Status != "" {
+				t.Fatalf("repo starred by other user should not change; got %v, %v", r, err)
+			}
+
+			rs, err := db.GetReposByTag("tag")
+			if err != nil {
+				t.Fatalf("failed to get repos by tag: %v", err)
+			}
+			found := false
+			for _, r := range rs {
+				found = found || r.ID == 2
+			}
+			if found != tc.found {
+				t.Fatalf("expected repo found by search to be %t", tc.found)
+			}
+
+			// starred again.
+			if tc.policy != storage.Delete {
+				if err := db.SetStarred("foo", []int{2}); err != nil {
+					t.Fatalf("failed to set starred: %v", err)
+				}
+				r, err := db.GetRepo(2)
+				if err != nil || r.Status != "" {
+					t.Fatalf("expected starred repo; got %v, %v", r, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnstarDeleteUnstarredByOthers(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	r := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com"}
+	if err := db.InsertRepo(r); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	for _, user := range []string{"foo", "bar"} {
+		if err := db.SetStarred(user, []int{1}); err != nil {
+			t.Fatalf("failed to set starred: %v", err)
+		}
+	}
+	if _, err := db.Unstar("bar", nil, storage.Mark); err != nil {
+		t.Fatalf("failed to unstar: %v", err)
+	}
+	if _, err := db.Unstar("foo", nil, storage.Delete); err != nil {
+		t.Fatalf("failed to unstar: %v", err)
+	}
+
+	// bar keeps the repo unstarred, so it is marked.
+	r, err = db.GetRepo(1)
+	if err != nil || r.Status != repo.StatusUnstarred {
+		t.Fatalf("expected repo with status %q; got %v, %v", repo.StatusUnstarred, r, err)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("database should be created")
+	}
+
+	for id := 1; id <= 2; id++ {
+		r := &repo.Repo{ID: id, Name: "Foo", URLHTTP: "http://something.com",
+			Tags: []string{"tag"}}
+		if err := db.InsertRepo(r); err != nil {
+			t.Fatalf("failed to insert repo: %v", err)
+		}
+	}
+	if err := db.SetStarred("foo", []int{1, 2}); err != nil {
+		t.Fatalf("failed to set starred: %v", err)
+	}
+	if err := db.SetCollection("acme", repo.CollectionOrg, []int{2}); err != nil {
+		t.Fatalf("failed to set collection: %v", err)
+	}
+
+	r, err := db.GetRepo(2)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if !tagsEq(r.Collections, []string{repo.CollectionOrg, repo.CollectionStarred}) {
+		t.Fatalf("expected repo in org and starred; got %v", r.Collections)
+	}
+
+	rs, err := db.GetReposByTagFrom("tag", repo.CollectionOrg)
+	if err != nil {
+		t.Fatalf("failed to get repos by tag: %v", err)
+	}
+	if len(rs) != 1 || rs[0].ID != 2 {
+		t.Fatalf("expected repo 2 of org; got %v", rs)
+	}
+
+	// the org repo is not archived when unstarred.
+	if _, err := db.Unstar("foo", nil, storage.Archive); err != nil {
+		t.Fatalf("failed to unstar: %v", err)
+	}
+	rs, err = db.GetReposByTagFrom("", "")
+	if err != nil {
+		t.Fatalf("failed to get repos: %v", err)
+	}
+	if len(rs) != 1 || rs[0].ID != 2 || !tagsEq(rs[0].Collections, []string{repo.CollectionOrg}) {
+		t.Fatalf("expected only repo 2 in org; got %v", rs)
+	}
+}
+
+func TestMigrateCollections(t *testing.T) {
+	f, err := ioutil.TempFile(".", "testNewDb")
+	if err != nil {
+		t.Fatalf("failed to create temp file")
+	}
+	defer os.Remove(f.Name())
+
+	// user_repo created before the collections.
+	database, err := sql.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = database.Exec(`CREATE TABLE user_repo (
+			user TEXT NOT NULL,
+			repo_id INTEGER NOT NULL,
+			status TEXT NOT NULL
+		);
+		CREATE UNIQUE INDEX user_repo_idx ON user_repo (user, repo_id);
+		INSERT INTO user_repo (user, repo_id, status) VALUES ("foo", 1, "starred");`)
+	if err != nil {
+		t.Fatalf("failed to create old table: %v", err)
+	}
+	database.Close()
+
+	db, err := New(f.Name())
+	if err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	defer db.(*service).Close()
+
+	r := &repo.Repo{ID: 1, Name: "Foo", URLHTTP: "http://something.com"}
+	if err := db.InsertRepo(r); err != nil {
+		t.Fatalf("failed to insert repo: %v", err)
+	}
+	if err := db.SetCollection("foo", repo.CollectionOwned, []int{1}); err != nil {
+		t.Fatalf("failed to set collection: %v", err)
+	}
+	r, err = db.GetRepo(1)
+	if err != nil {
+		t.Fatalf("failed to get repo: %v", err)
+	}
+	if !tagsEq(r.Collections, []string{repo.CollectionOwned, repo.CollectionStarred}) {
+		t.Fatalf("expected owned and starred repo; got %v", r.Collections)
+	}
+}
@@ -1,18 +1,186 @@
 package storage
 
-import "github.com/rschio/repoTagger/repo"
+import (
+	"fmt"
+	"time"
+
+	"github.com/rschio/repoTagger/repo"
+)
 
 // Storage is the interface that abstract the data storage.
 type Storage interface {
 	// InsertRepo insert the repository into storage.
 	InsertRepo(*repo.Repo) error
+	// UpsertRepo insert the repository or update the
+	// metadata and topics of the stored one, keeping
+	// its tags.
+	UpsertRepo(*repo.Repo) (UpsertResult, error)
 	// GetReposByTag search all the repositories that has
-	// a tag starting with string and return the repositories
-	// slice and error.
+	// a tag or topic starting with string and return the
+	// repositories slice and error.
 	GetReposByTag(string) ([]*repo.Repo, error)
+	// GetReposByTagFrom is like GetReposByTag but only
+	// search the repositories of collection.
+	GetReposByTagFrom(tag, collection string) ([]*repo.Repo, error)
 	// UpdateTags delete the old tags of r and
 	// set the new ones.
 	UpdateTags(r *repo.Repo) error
 	// GetRepo returns the repo by id.
 	GetRepo(id int) (*repo.Repo, error)
+	// GetSyncState returns the state of the last sync
+	// of user, a user never synced has an empty state.
+	GetSyncState(user string) (*repo.SyncState, error)
+	// SetSyncState stores the sync state of its user.
+	SetSyncState(*repo.SyncState) error
+	// SetStarred records that user starred the repositories
+	// of ids, they lose the unstarred or archived status.
+	SetStarred(user string, ids []int) error
+	// SetCollection records that the repositories of ids
+	// are in collection of user, like SetStarred does
+	// to repo.CollectionStarred.
+	SetCollection(user, collection string, ids []int) error
+	// GetReadme returns the README of the repository
+	// repoID, or nil if it has none.
+	GetReadme(repoID int) (*repo.Readme, error)
+	// SetReadme stores the README of the repository
+	// repoID and indexes its text.
+	SetReadme(repoID int, readme *repo.Readme) error
+	// SetReleasedAt stores when the latest release of
+	// the repository repoID was published.
+	SetReleasedAt(repoID int, at time.Time) error
+	// SetSystemTags replaces the system tags of the
+	// repository repoID, its tags are not changed.
+	SetSystemTags(repoID int, tags []string) error
+	// SearchText returns the repositories whose README
+	// matches the full-text query. A malformed query
+	// returns QueryErr.
+	SearchText(query string) ([]*repo.Repo, error)
+	// CreateJob stores the new job and sets its id.
+	CreateJob(*Job) error
+	// UpdateJob stores the progress of the job.
+	UpdateJob(*Job) error
+	// GetJob returns the job by id.
+	GetJob(id int) (*Job, error)
+	// FailRunningJobs marks the running jobs as failed
+	// and returns how many they were.
+	FailRunningJobs() (int, error)
+	// SetFeedback stores the feedback, replacing the
+	// earlier one to the same repository and tag.
+	SetFeedback(*Feedback) error
+	// GetFeedback returns the feedback to the
+	// repository repoID.
+	GetFeedback(repoID int) ([]*Feedback, error)
+	// GetPrecision returns the precision of each
+	// rule with feedback, sorted by rule.
+	GetPrecision() ([]*Precision, error)
+	// Unstar finds the repositories recorded as starred by
+	// user that are not in starred, applies policy to them
+	// and returns their ids. The policy is only applied to
+	// repositories that no other user starred.
+	Unstar(user string, starred []int, policy UnstarPolicy) ([]int, error)
+}
+
+// QueryErr is the error of a malformed search query.
+type QueryErr string
+
+func (e QueryErr) Error() string {
+	return string(e)
+}
+
+// UpsertResult is what UpsertRepo did with a repository.
+type UpsertResult int
+
+// Results of UpsertRepo.
+const (
+	Inserted UpsertResult = iota + 1
+	Updated
+	Unchanged
+)
+
+func (r UpsertResult) String() string {
+	switch r {
+	case Inserted:
+		return "inserted"
+	case Updated:
+		return "updated"
+	case Unchanged:
+		return "unchanged"
+	}
+	return "failed"
+}
+
+// UpsertCount counts the results of UpsertRepos.
+type UpsertCount struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// UpsertRepos upserts all the repositories rs into s, counts
+// the results and returns the repositories stored. It does not
+// stop on errors, the repositories that failed are counted as
+// Failed.
+func UpsertRepos(s Storage, rs []*repo.Repo) (*UpsertCount, []*repo.Repo) {
+	_, count, stored := UpsertReport(s, rs)
+	return count, stored
+}
+
+// RepoResult is the result of upserting one repository.
+type RepoResult struct {
+	ID       int    `json:"id"`
+	FullName string `json:"full_name"`
+	// Result is inserted, updated, unchanged or failed.
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// UpsertReport is like UpsertRepos but also reports
+// the result of each repository.
+func UpsertReport(s Storage, rs []*repo.Repo) ([]RepoResult, *UpsertCount, []*repo.Repo) {
+	count := &UpsertCount{}
+	report := make([]RepoResult, len(rs))
+	stored := make([]*repo.Repo, 0, len(rs))
+	for i, r := range rs {
+		res, err := s.UpsertRepo(r)
+		report[i] = RepoResult{ID: r.ID, FullName: r.FullName, Result: res.String()}
+		if err != nil {
+			report[i].Error = err.Error()
+			count.Failed++
+			continue
+		}
+		stored = append(stored, r)
+		switch res {
+		case Inserted:
+			count.Inserted++
+		case Updated:
+			count.Updated++
+		case Unchanged:
+			count.Unchanged++
+		}
+	}
+	return report, count, stored
+}
+
+// UnstarPolicy is what to do with the repositories
+// that are no longer starred.
+type UnstarPolicy string
+
+const (
+	// Mark sets the status of the repositories to unstarred.
+	Mark UnstarPolicy = "mark"
+	// Archive sets the status of the repositories to archived.
+	Archive UnstarPolicy = "archive"
+	// Delete deletes the repositories and their tags, the ones
+	// still kept unstarred by other users are marked.
+	Delete UnstarPolicy = "delete"
+)
+
+// ParseUnstarPolicy returns the policy named s.
+func ParseUnstarPolicy(s string) (UnstarPolicy, error) {
+	switch p := UnstarPolicy(s); p {
+	case Mark, Archive, Delete:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid unstar policy %q", s)
 }